@@ -97,7 +97,7 @@ type BlockChain struct {
 	db                  database.DB
 	chainParams         *chaincfg.Params
 	timeSource          MedianTimeSource
-	sigCache            *txscript.SigCache
+	sigCache            txscript.SignatureCache
 	indexManager        IndexManager
 	hashCache           *txscript.HashCache
 
@@ -1519,7 +1519,7 @@ type Config struct {
 	//
 	// This field can be nil if the caller is not interested in using a
 	// signature cache.
-	SigCache *txscript.SigCache
+	SigCache txscript.SignatureCache
 
 	// IndexManager defines an index manager to use when initializing the
 	// chain and connecting and disconnecting blocks.
@@ -57,15 +57,10 @@ func nextPowerOfTwo(n int) int {
 
 // HashMerkleBranches takes two hashes, treated as the left and right tree
 // nodes, and returns the hash of their concatenation.  This is a helper
-// function used to aid in the generation of a merkle tree.
+// function used to aid in the generation of a merkle tree.  It is equivalent
+// to chainhash.HashMerkleBranches.
 func HashMerkleBranches(left *chainhash.Hash, right *chainhash.Hash) *chainhash.Hash {
-	// Concatenate the left and right nodes.
-	var hash [chainhash.HashSize * 2]byte
-	copy(hash[:chainhash.HashSize], left[:])
-	copy(hash[chainhash.HashSize:], right[:])
-
-	newHash := chainhash.DoubleHashH(hash[:])
-	return &newHash
+	return chainhash.HashMerkleBranches(left, right)
 }
 
 // BuildMerkleTreeStore creates a merkle tree from a slice of transactions,
@@ -32,7 +32,7 @@ type txValidator struct {
 	resultChan   chan error
 	utxoView     *UtxoViewpoint
 	flags        txscript.ScriptFlags
-	sigCache     *txscript.SigCache
+	sigCache     txscript.SignatureCache
 	hashCache    *txscript.HashCache
 }
 
@@ -188,7 +188,7 @@ func (v *txValidator) Validate(items []*txValidateItem) error {
 // newTxValidator returns a new instance of txValidator to be used for
 // validating transaction scripts asynchronously.
 func newTxValidator(utxoView *UtxoViewpoint, flags txscript.ScriptFlags,
-	sigCache *txscript.SigCache, hashCache *txscript.HashCache) *txValidator {
+	sigCache txscript.SignatureCache, hashCache *txscript.HashCache) *txValidator {
 	return &txValidator{
 		validateChan: make(chan *txValidateItem),
 		quitChan:     make(chan struct{}),
@@ -203,7 +203,7 @@ func newTxValidator(utxoView *UtxoViewpoint, flags txscript.ScriptFlags,
 // ValidateTransactionScripts validates the scripts for the passed transaction
 // using multiple goroutines.
 func ValidateTransactionScripts(tx *navutil.Tx, utxoView *UtxoViewpoint,
-	flags txscript.ScriptFlags, sigCache *txscript.SigCache,
+	flags txscript.ScriptFlags, sigCache txscript.SignatureCache,
 	hashCache *txscript.HashCache) error {
 
 	// First determine if segwit is active according to the scriptFlags. If
@@ -255,7 +255,7 @@ func ValidateTransactionScripts(tx *navutil.Tx, utxoView *UtxoViewpoint,
 // checkBlockScripts executes and validates the scripts for all transactions in
 // the passed block using multiple goroutines.
 func checkBlockScripts(block *navutil.Block, utxoView *UtxoViewpoint,
-	scriptFlags txscript.ScriptFlags, sigCache *txscript.SigCache,
+	scriptFlags txscript.ScriptFlags, sigCache txscript.SignatureCache,
 	hashCache *txscript.HashCache) error {
 
 	// First determine if segwit is active according to the scriptFlags. If
@@ -6,8 +6,14 @@
 package chainhash
 
 import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/binary"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"math/big"
+	"math/bits"
 )
 
 // HashSize of array used to store hashes.  See Hash.
@@ -17,9 +23,14 @@ const HashSize = 32
 const MaxHashStringSize = HashSize * 2
 
 // ErrHashStrSize describes an error that indicates the caller specified a hash
-// string that has too many characters.
+// string that has too many characters, or one that doesn't have exactly
+// MaxHashStringSize characters when parsing strictly.
 var ErrHashStrSize = fmt.Errorf("max hash string length is %v bytes", MaxHashStringSize)
 
+// ErrHashStrInvalid describes an error that indicates the caller specified a
+// hash string that contains characters which are not hexadecimal digits.
+var ErrHashStrInvalid = errors.New("hash string contains non-hexadecimal characters")
+
 // Hash is used in several of the navcoin messages and common structures.  It
 // typically represents the double sha256 of data.
 type Hash [HashSize]byte
@@ -27,12 +38,105 @@ type Hash [HashSize]byte
 // String returns the Hash as the hexadecimal string of the byte-reversed
 // hash.
 func (hash Hash) String() string {
-	for i := 0; i < HashSize/2; i++ {
-		hash[i], hash[HashSize-1-i] = hash[HashSize-1-i], hash[i]
-	}
+	ReverseBytes(hash[:])
 	return hex.EncodeToString(hash[:])
 }
 
+// shortHashChars is the number of hexadecimal characters Short keeps from each
+// end of the string of a hash.
+const shortHashChars = 6
+
+// Short returns an abbreviated form of the string of the hash which is meant
+// for compact log lines.  It consists of the first and last 6 characters of
+// String joined by an ellipsis, for example 000000…33e506.
+func (hash *Hash) Short() string {
+	return hash.ShortN(shortHashChars)
+}
+
+// ShortN returns an abbreviated form of the string of the hash consisting of
+// its first and last n characters, in the same byte-reversed order as String,
+// joined by an ellipsis.  The full string is returned when n is not positive
+// or the abbreviation would not be shorter than it.
+func (hash *Hash) ShortN(n int) string {
+	s := hash.String()
+	if n <= 0 || 2*n >= len(s) {
+		return s
+	}
+	return s[:n] + "…" + s[len(s)-n:]
+}
+
+// Reverse returns a new Hash with the bytes of the hash in reverse order.  The
+// hash itself is not modified.
+//
+// NOTE: Hashes are displayed, for example by String, in the reverse of the
+// order they are stored and serialized in.
+func (hash *Hash) Reverse() Hash {
+	reversed := *hash
+	ReverseBytes(reversed[:])
+	return reversed
+}
+
+// ReverseBytes reverses the order of the passed bytes in place.
+func ReverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+// ToBig returns the hash interpreted as a 256-bit unsigned number, which is how
+// hashes are compared against proof of work targets.  A Hash is stored in
+// little-endian, so its last byte is the most significant one, and its String
+// is the big-endian hexadecimal representation of the number.
+func (hash *Hash) ToBig() *big.Int {
+	// The big package wants the bytes in big-endian, so reverse them.
+	buf := *hash
+	ReverseBytes(buf[:])
+	return new(big.Int).SetBytes(buf[:])
+}
+
+// HashFromBig returns the Hash which represents the passed number as returned
+// by ToBig.  The number must be non-negative and less than 2^256.  The sign of
+// negative numbers is ignored and only the least significant 256 bits of
+// larger numbers are kept.
+func HashFromBig(n *big.Int) Hash {
+	// Copy the words of the number, which are ordered from least to most
+	// significant, directly into the little-endian hash to avoid
+	// allocating.
+	var hash Hash
+	i := 0
+	for _, word := range n.Bits() {
+		for j := 0; j < bits.UintSize/8 && i < HashSize; j++ {
+			hash[i] = byte(word)
+			word >>= 8
+			i++
+		}
+	}
+	return hash
+}
+
+// CompareWork compares the passed hashes interpreted as 256-bit unsigned
+// numbers like ToBig, which is the big-endian number their String represents,
+// and returns -1 when a is less than b, 0 when they are equal and 1 when a is
+// greater than b.  It is equivalent to a.ToBig().Cmp(b.ToBig()) without
+// allocating.
+//
+// Only the raw magnitudes are compared, so callers decide what a greater number
+// means.  For example, a proof of work hash which is less than another
+// represents more work, while a greater cumulative work value stored in a Hash
+// represents more work.
+func CompareWork(a, b *Hash) int {
+	// The last byte is the most significant one.
+	for i := HashSize - 1; i >= 0; i-- {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
 // CloneBytes returns a copy of the bytes which represent the hash as a byte
 // slice.
 //
@@ -45,6 +149,43 @@ func (hash *Hash) CloneBytes() []byte {
 	return newHash
 }
 
+// HasPrefix returns whether the bytes of the hash begin with the passed prefix.
+// The prefix is matched against the bytes in the order they are stored, which
+// is the order of hash[:] and of database keys made from the hash, rather than
+// the byte-reversed order of its String.  An empty prefix matches every hash.
+func (hash *Hash) HasPrefix(prefix []byte) bool {
+	return bytes.HasPrefix(hash[:], prefix)
+}
+
+// ComparePrefix compares the passed byte slices, such as a database key and a
+// prefix of the keys being scanned, over the length of the shorter one.  It
+// returns 0 when either is a prefix of the other, and otherwise -1 or 1 as
+// bytes.Compare does when a sorts before or after b respectively.  Given the
+// prefix as b, it therefore returns -1 for keys before the range of keys with
+// the prefix, 0 for keys within it and 1 for keys after it.
+//
+// Like HasPrefix, the bytes are compared in the order they are passed, so
+// prefixes of hashes must be taken from the stored bytes of the hashes.
+func ComparePrefix(a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	return bytes.Compare(a[:n], b[:n])
+}
+
+// OutpointBytes returns the canonical 36-byte serialization of the outpoint
+// which references the output at the passed index of the transaction with the
+// passed hash.  It consists of the bytes of the hash, in the order they are
+// stored in, followed by the index as a little-endian uint32, which is how
+// outpoints are serialized on the wire and what bloom filters match them by.
+func OutpointBytes(txHash *Hash, index uint32) []byte {
+	b := make([]byte, HashSize+4)
+	copy(b, txHash[:])
+	binary.LittleEndian.PutUint32(b[HashSize:], index)
+	return b
+}
+
 // SetBytes sets the bytes which represent the hash.  An error is returned if
 // the number of bytes passed in is not HashSize.
 func (hash *Hash) SetBytes(newHash []byte) error {
@@ -69,6 +210,33 @@ func (hash *Hash) IsEqual(target *Hash) bool {
 	return *hash == *target
 }
 
+// MarshalText returns the Hash encoded as the hexadecimal string of the
+// byte-reversed hash, the same form as String.  This allows hashes to be
+// encoded as strings by packages such as encoding/json.
+func (hash Hash) MarshalText() ([]byte, error) {
+	return []byte(hash.String()), nil
+}
+
+// UnmarshalText sets the hash from the hexadecimal string of a byte-reversed
+// hash as returned by MarshalText.  Unlike NewHashFromStr, the text must be
+// exactly MaxHashStringSize hexadecimal characters.
+func (hash *Hash) UnmarshalText(text []byte) error {
+	return decodeStrict(hash, string(text))
+}
+
+// EqualConstantTime returns true if target is the same as hash.  Unlike
+// IsEqual, the time taken to compare the hashes does not depend on their
+// contents, so it should be preferred when either hash is derived from secret
+// data, such as an authentication tag, where leaking the position of the first
+// mismatching byte through timing could aid an attacker.  IsEqual remains the
+// better choice for public data such as block and transaction hashes.
+func (hash *Hash) EqualConstantTime(target *Hash) bool {
+	if hash == nil || target == nil {
+		return hash == target
+	}
+	return subtle.ConstantTimeCompare(hash[:], target[:]) == 1
+}
+
 // NewHash returns a new Hash from a byte slice.  An error is returned if
 // the number of bytes passed in is not HashSize.
 func NewHash(newHash []byte) (*Hash, error) {
@@ -80,9 +248,23 @@ func NewHash(newHash []byte) (*Hash, error) {
 	return &sh, err
 }
 
+// NewHashFromWire returns a new Hash from the passed bytes in the internal byte
+// order used on the wire and in storage, without reversing them.  It is the
+// same as NewHash and exists to make the byte order explicit at call sites
+// which decode hashes from raw data, as opposed to parsing the byte-reversed
+// display form of a hash with NewHashFromStrStrict.  An error is returned if the
+// number of bytes passed in is not HashSize.
+func NewHashFromWire(b []byte) (*Hash, error) {
+	return NewHash(b)
+}
+
 // NewHashFromStr creates a Hash from a hash string.  The string should be
 // the hexadecimal string of a byte-reversed hash, but any missing characters
 // result in zero padding at the end of the Hash.
+//
+// NOTE: Since short strings are padded, a truncated hash string is silently
+// accepted as a different hash.  Use NewHashFromStrStrict when parsing hashes
+// from untrusted input such as API requests.
 func NewHashFromStr(hash string) (*Hash, error) {
 	ret := new(Hash)
 	err := Decode(ret, hash)
@@ -92,8 +274,33 @@ func NewHashFromStr(hash string) (*Hash, error) {
 	return ret, nil
 }
 
+// NewHashFromStrStrict creates a Hash from a hash string.  Unlike
+// NewHashFromStr, the string must be the full hexadecimal string of a
+// byte-reversed hash.  That is, exactly MaxHashStringSize hexadecimal
+// characters.  Shorter strings are rejected rather than zero padded.
+func NewHashFromStrStrict(hash string) (*Hash, error) {
+	ret := new(Hash)
+	err := decodeStrict(ret, hash)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// decodeStrict decodes the byte-reversed hexadecimal string encoding of a Hash
+// to a destination.  ErrHashStrSize is returned if the string is not exactly
+// MaxHashStringSize characters.
+func decodeStrict(dst *Hash, src string) error {
+	if len(src) != MaxHashStringSize {
+		return ErrHashStrSize
+	}
+	return Decode(dst, src)
+}
+
 // Decode decodes the byte-reversed hexadecimal string encoding of a Hash to a
-// destination.
+// destination.  ErrHashStrSize is returned if the string is too long and
+// ErrHashStrInvalid if it contains non-hexadecimal characters, so callers can
+// tell the failures apart.
 func Decode(dst *Hash, src string) error {
 	// Return error if hash string is too long.
 	if len(src) > MaxHashStringSize {
@@ -115,7 +322,7 @@ func Decode(dst *Hash, src string) error {
 	var reversedHash Hash
 	_, err := hex.Decode(reversedHash[HashSize-hex.DecodedLen(len(srcBytes)):], srcBytes)
 	if err != nil {
-		return err
+		return ErrHashStrInvalid
 	}
 
 	// Reverse copy from the temporary hash to destination.  Because the
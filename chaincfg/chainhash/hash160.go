@@ -0,0 +1,74 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Hash160Size is the size of the array used to store a Hash160.
+const Hash160Size = ripemd160.Size
+
+// Hash160 is the ripemd160(sha256(b)) hash of data such as a public key or a
+// script, which identifies the owner of pay-to-pubkey-hash and
+// pay-to-script-hash outputs.  It is a distinct type from Hash so that a 20-byte
+// pubkey or script hash can't accidentally be used where a block or transaction
+// hash is expected, or vice versa.
+type Hash160 [Hash160Size]byte
+
+// String returns the Hash160 as a hexadecimal string.  Unlike the String of a
+// Hash, the bytes are not reversed, which matches how pubkey and script hashes
+// appear in scripts.
+func (hash Hash160) String() string {
+	return hex.EncodeToString(hash[:])
+}
+
+// CloneBytes returns a copy of the bytes which represent the hash as a byte
+// slice.
+func (hash *Hash160) CloneBytes() []byte {
+	newHash := make([]byte, Hash160Size)
+	copy(newHash, hash[:])
+
+	return newHash
+}
+
+// SetBytes sets the bytes which represent the hash.  An error is returned if
+// the number of bytes passed in is not Hash160Size.
+func (hash *Hash160) SetBytes(newHash []byte) error {
+	nhlen := len(newHash)
+	if nhlen != Hash160Size {
+		return fmt.Errorf("invalid hash160 length of %v, want %v", nhlen,
+			Hash160Size)
+	}
+	copy(hash[:], newHash)
+
+	return nil
+}
+
+// Hash160B calculates ripemd160(sha256(b)) and returns the resulting bytes.
+// This is how the pubkey hash of a pay-to-pubkey-hash address is derived from
+// the serialized public key, and the script hash of a pay-to-script-hash
+// address from the redeem script.
+func Hash160B(b []byte) []byte {
+	first := sha256.Sum256(b)
+	h := ripemd160.New()
+	h.Write(first[:])
+	return h.Sum(nil)
+}
+
+// Hash160H calculates ripemd160(sha256(b)) and returns the resulting bytes as a
+// Hash160.
+func Hash160H(b []byte) Hash160 {
+	first := sha256.Sum256(b)
+	var hash Hash160
+	h := ripemd160.New()
+	h.Write(first[:])
+	h.Sum(hash[:0])
+	return hash
+}
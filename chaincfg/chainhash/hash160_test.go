@@ -0,0 +1,110 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// TestHash160 tests the Hash160 API.
+func TestHash160(t *testing.T) {
+	// The hash160 of the compressed public key of the secp256k1 generator
+	// point, which is the private key 1.
+	pubKey, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029" +
+		"bfcdb2dce28d959f2815b16f81798")
+	const wantStr = "751e76e8199196d454941c45d1b3a323f1433bd6"
+
+	var hash Hash160
+	if err := hash.SetBytes(Hash160B(pubKey)); err != nil {
+		t.Fatalf("SetBytes: unexpected error: %v", err)
+	}
+	if got := hash.String(); got != wantStr {
+		t.Fatalf("String: got %s, want %s", got, wantStr)
+	}
+
+	// CloneBytes must return a copy of the bytes.
+	clone := hash.CloneBytes()
+	if !bytes.Equal(clone, hash[:]) {
+		t.Fatalf("CloneBytes: got %x, want %x", clone, hash[:])
+	}
+	clone[0] ^= 0xff
+	if hash.String() != wantStr {
+		t.Fatalf("CloneBytes: modifying the copy changed the hash")
+	}
+
+	// Only slices of exactly Hash160Size bytes may be set.
+	for _, size := range []int{0, Hash160Size - 1, Hash160Size + 1, HashSize} {
+		err := hash.SetBytes(make([]byte, size))
+		if err == nil {
+			t.Errorf("SetBytes (size %d): unexpected success", size)
+		}
+	}
+	if hash.String() != wantStr {
+		t.Fatalf("SetBytes: failed call changed the hash")
+	}
+}
+
+// TestHash160B ensures Hash160B returns ripemd160(sha256(b)).
+func TestHash160B(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "b472a266d0bd89c13706a4132ccfb16f7c3b9fcb"},
+		{"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			"751e76e8199196d454941c45d1b3a323f1433bd6"},
+	}
+
+	for _, test := range tests {
+		in, err := hex.DecodeString(test.in)
+		if err != nil {
+			t.Fatalf("DecodeString: %v", err)
+		}
+		if got := hex.EncodeToString(Hash160B(in)); got != test.want {
+			t.Errorf("Hash160B(%s) = %s, want %s", test.in, got,
+				test.want)
+		}
+	}
+}
+
+// TestHash160Funcs ensures the hash160 functions derive the expected pubkey
+// hashes from known compressed and uncompressed public keys.
+func TestHash160Funcs(t *testing.T) {
+	tests := []struct {
+		name   string
+		pubKey string
+		want   string
+	}{
+		// The public keys of the secp256k1 generator point, which is
+		// the private key 1.
+		{"compressed",
+			"0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b" +
+				"16f81798",
+			"751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"uncompressed",
+			"0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b" +
+				"16f81798483ada7726a3c4655da4fbfc0e1108a8fd17b448a6855419" +
+				"9c47d08ffb10d4b8",
+			"91b24bf9f5288532960ac687abb035127b1d28a5"},
+	}
+
+	for _, test := range tests {
+		pubKey, err := hex.DecodeString(test.pubKey)
+		if err != nil {
+			t.Fatalf("DecodeString: %v", err)
+		}
+
+		if got := hex.EncodeToString(Hash160B(pubKey)); got != test.want {
+			t.Errorf("Hash160B (%s) = %s, want %s", test.name, got,
+				test.want)
+		}
+		if got := Hash160H(pubKey).String(); got != test.want {
+			t.Errorf("Hash160H (%s) = %s, want %s", test.name, got,
+				test.want)
+		}
+	}
+}
@@ -6,7 +6,10 @@ package chainhash
 
 import (
 	"bytes"
+	"crypto/rand"
 	"encoding/hex"
+	"encoding/json"
+	"math/big"
 	"testing"
 )
 
@@ -76,6 +79,16 @@ func TestHash(t *testing.T) {
 	if hash.IsEqual(nil) {
 		t.Error("IsEqual: non-nil hash matches nil hash")
 	}
+	if (*Hash)(nil).IsEqual(hash) {
+		t.Error("IsEqual: nil hash matches non-nil hash")
+	}
+
+	// Ensure the bytes returned by CloneBytes don't alias the hash.
+	cloned := hash.CloneBytes()
+	cloned[0] ^= 0xff
+	if cloned[0] == hash[0] {
+		t.Error("CloneBytes: modifying the copy modified the hash")
+	}
 
 	// Invalid size for SetBytes.
 	err = hash.SetBytes([]byte{0x00})
@@ -91,6 +104,108 @@ func TestHash(t *testing.T) {
 	}
 }
 
+// TestHashSetBytes ensures SetBytes only accepts slices of exactly HashSize
+// bytes and leaves the hash untouched otherwise.
+func TestHashSetBytes(t *testing.T) {
+	full := make([]byte, HashSize)
+	for i := range full {
+		full[i] = byte(i + 1)
+	}
+
+	tests := []struct {
+		name  string
+		in    []byte
+		valid bool
+	}{
+		{"correct length", full, true},
+		{"short", full[:HashSize-1], false},
+		{"long", append(full, 0x00), false},
+		{"empty", []byte{}, false},
+		{"nil", nil, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		hash := mainNetGenesisHash
+		err := hash.SetBytes(test.in)
+		if (err == nil) != test.valid {
+			t.Errorf("SetBytes (%s): unexpected error state: %v",
+				test.name, err)
+			continue
+		}
+		if !test.valid {
+			if hash != mainNetGenesisHash {
+				t.Errorf("SetBytes (%s): hash modified on error",
+					test.name)
+			}
+			continue
+		}
+		if !bytes.Equal(hash[:], test.in) {
+			t.Errorf("SetBytes (%s): got %x, want %x", test.name,
+				hash[:], test.in)
+		}
+	}
+}
+
+// TestOutpointBytes ensures outpoints are serialized to the expected bytes.
+func TestOutpointBytes(t *testing.T) {
+	// The first input of the first transaction to spend a coinbase output,
+	// in block 170 of the bitcoin main network.
+	txHash, err := NewHashFromStr("0437cd7f8525ceed2324359c2d0ba26006d92d856a9c20fa0241106ee5a597c9")
+	if err != nil {
+		t.Fatalf("NewHashFromStr: %v", err)
+	}
+	want := []byte{
+		0xc9, 0x97, 0xa5, 0xe5, 0x6e, 0x10, 0x41, 0x02,
+		0xfa, 0x20, 0x9c, 0x6a, 0x85, 0x2d, 0xd9, 0x06,
+		0x60, 0xa2, 0x0b, 0x2d, 0x9c, 0x35, 0x24, 0x23,
+		0xed, 0xce, 0x25, 0x85, 0x7f, 0xcd, 0x37, 0x04,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	if got := OutpointBytes(txHash, 0); !bytes.Equal(got, want) {
+		t.Errorf("OutpointBytes: got %x, want %x", got, want)
+	}
+
+	want[32], want[33], want[35] = 0x01, 0x02, 0xff
+	got := OutpointBytes(txHash, 0xff000201)
+	if !bytes.Equal(got, want) {
+		t.Errorf("OutpointBytes: got %x, want %x", got, want)
+	}
+}
+
+// TestHashEqualConstantTime ensures EqualConstantTime always agrees with
+// IsEqual.
+func TestHashEqualConstantTime(t *testing.T) {
+	same := mainNetGenesisHash
+	first := mainNetGenesisHash
+	last := mainNetGenesisHash
+	first[0] ^= 0x01
+	last[HashSize-1] ^= 0x80
+
+	tests := []struct {
+		name string
+		a, b *Hash
+	}{
+		{"same pointer", &mainNetGenesisHash, &mainNetGenesisHash},
+		{"same contents", &mainNetGenesisHash, &same},
+		{"zero hashes", &Hash{}, &Hash{}},
+		{"first byte differs", &mainNetGenesisHash, &first},
+		{"last byte differs", &mainNetGenesisHash, &last},
+		{"nil and non-nil", nil, &mainNetGenesisHash},
+		{"non-nil and nil", &mainNetGenesisHash, nil},
+		{"both nil", nil, nil},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		want := test.a.IsEqual(test.b)
+		if got := test.a.EqualConstantTime(test.b); got != want {
+			t.Errorf("EqualConstantTime (%s): got %v, want %v",
+				test.name, got, want)
+		}
+	}
+}
+
 // TestHashString  tests the stringized output for hashes.
 func TestHashString(t *testing.T) {
 	// Block 100000 hash.
@@ -109,6 +224,271 @@ func TestHashString(t *testing.T) {
 	}
 }
 
+// TestHashShort ensures the abbreviated strings of a hash are taken from the
+// ends of the byte-reversed string returned by String.
+func TestHashShort(t *testing.T) {
+	// Block 100000 hash.
+	hash := Hash([HashSize]byte{ // Make go vet happy.
+		0x06, 0xe5, 0x33, 0xfd, 0x1a, 0xda, 0x86, 0x39,
+		0x1f, 0x3f, 0x6c, 0x34, 0x32, 0x04, 0xb0, 0xd2,
+		0x78, 0xd4, 0xaa, 0xec, 0x1c, 0x0b, 0x20, 0xaa,
+		0x27, 0xba, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00,
+	})
+	fullStr := "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506"
+
+	if got, want := hash.Short(), "000000…33e506"; got != want {
+		t.Errorf("Short: got %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		n    int
+		want string
+	}{
+		{1, "0…6"},
+		{4, "0000…e506"},
+		{10, "0000000000…1afd33e506"},
+		{31, fullStr[:31] + "…" + fullStr[33:]},
+		{32, fullStr},
+		{100, fullStr},
+		{0, fullStr},
+		{-1, fullStr},
+	}
+	for _, test := range tests {
+		if got := hash.ShortN(test.n); got != test.want {
+			t.Errorf("ShortN(%d): got %v, want %v", test.n, got,
+				test.want)
+		}
+	}
+}
+
+// TestHashBig ensures converting hashes to and from big.Int numbers treats the
+// hashes as little-endian and round trips.
+func TestHashBig(t *testing.T) {
+	// The target represented by compact bits 0x1d00ffff.
+	targetStr := "00000000ffff0000000000000000000000000000000000000000000000000000"
+	target, err := NewHashFromStr(targetStr)
+	if err != nil {
+		t.Fatalf("NewHashFromStr: %v", err)
+	}
+	want := new(big.Int).Lsh(big.NewInt(0xffff), 208)
+	if got := target.ToBig(); got.Cmp(want) != 0 {
+		t.Fatalf("ToBig: got %x, want %x", got, want)
+	}
+	if got := HashFromBig(want); got != *target {
+		t.Fatalf("HashFromBig: got %v, want %v", got, target)
+	}
+
+	oneLsh256 := new(big.Int).Lsh(big.NewInt(1), 256)
+	tests := []struct {
+		in   *big.Int
+		want Hash
+	}{
+		{big.NewInt(0), Hash{}},
+		{big.NewInt(1), Hash{0x01}},
+		{big.NewInt(0x0102), Hash{0x02, 0x01}},
+		{new(big.Int).Sub(oneLsh256, big.NewInt(1)), Hash{
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+			0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		}},
+
+		// Only the magnitude and the low 256 bits are kept.
+		{big.NewInt(-5), Hash{0x05}},
+		{new(big.Int).Add(oneLsh256, big.NewInt(5)), Hash{0x05}},
+	}
+	for i, test := range tests {
+		if got := HashFromBig(test.in); got != test.want {
+			t.Errorf("HashFromBig #%d (%x): got %v, want %v", i,
+				test.in, got, test.want)
+		}
+	}
+
+	// Random hashes must round trip.
+	for i := 0; i < 100; i++ {
+		var hash Hash
+		if _, err := rand.Read(hash[:]); err != nil {
+			t.Fatalf("unable to read random bytes: %v", err)
+		}
+		if got := HashFromBig(hash.ToBig()); got != hash {
+			t.Fatalf("round trip of %v: got %v", hash, got)
+		}
+	}
+}
+
+// TestCompareWork ensures CompareWork orders hashes by the numbers they
+// represent, which agrees with comparing the numbers returned by ToBig.
+func TestCompareWork(t *testing.T) {
+	maxHash := HashFromBig(new(big.Int).Sub(new(big.Int).Lsh(
+		big.NewInt(1), 256), big.NewInt(1)))
+	tests := []struct {
+		a, b Hash
+		want int
+	}{
+		{Hash{}, Hash{}, 0},
+		{maxHash, maxHash, 0},
+		{Hash{}, Hash{0x01}, -1},
+		{Hash{0x01}, Hash{}, 1},
+		{Hash{}, maxHash, -1},
+		{maxHash, Hash{}, 1},
+
+		// The last byte is the most significant one.
+		{Hash{0xff}, Hash{31: 0x01}, -1},
+		{Hash{31: 0x01}, Hash{0xff}, 1},
+		{Hash{0x02, 31: 0x01}, Hash{0x01, 31: 0x01}, 1},
+		{Hash{30: 0xff}, Hash{31: 0x01}, -1},
+	}
+
+	for i, test := range tests {
+		if got := CompareWork(&test.a, &test.b); got != test.want {
+			t.Errorf("CompareWork #%d: got %d, want %d", i, got,
+				test.want)
+		}
+	}
+
+	// Random hashes must compare like the numbers returned by ToBig.
+	for i := 0; i < 100; i++ {
+		var a, b Hash
+		if _, err := rand.Read(a[:]); err != nil {
+			t.Fatalf("unable to read random bytes: %v", err)
+		}
+
+		// Only change a single byte so the more significant bytes of
+		// the hashes are usually equal.
+		b = a
+		var buf [1]byte
+		if _, err := rand.Read(buf[:]); err != nil {
+			t.Fatalf("unable to read random bytes: %v", err)
+		}
+		b[i%HashSize] = buf[0]
+
+		want := a.ToBig().Cmp(b.ToBig())
+		if got := CompareWork(&a, &b); got != want {
+			t.Fatalf("CompareWork(%v, %v): got %d, want %d", a, b,
+				got, want)
+		}
+	}
+}
+
+// TestHashHasPrefix ensures HasPrefix matches prefixes of various lengths
+// against the stored bytes of a hash.
+func TestHashHasPrefix(t *testing.T) {
+	hash := mainNetGenesisHash
+	tests := []struct {
+		prefix []byte
+		want   bool
+	}{
+		{nil, true},
+		{[]byte{}, true},
+		{hash[:1], true},
+		{hash[:4], true},
+		{hash[:HashSize], true},
+		{[]byte{hash[0] + 1}, false},
+		{[]byte{hash[0], hash[1] + 1}, false},
+		{append(hash[:HashSize:HashSize], 0x00), false},
+
+		// The String of the hash begins with its last byte.
+		{[]byte{hash[HashSize-1]}, false},
+	}
+
+	for i, test := range tests {
+		if got := hash.HasPrefix(test.prefix); got != test.want {
+			t.Errorf("HasPrefix #%d (%x): got %v, want %v", i,
+				test.prefix, got, test.want)
+		}
+	}
+}
+
+// TestComparePrefix ensures ComparePrefix orders keys against prefixes of
+// various lengths.
+func TestComparePrefix(t *testing.T) {
+	tests := []struct {
+		a, b []byte
+		want int
+	}{
+		{nil, nil, 0},
+		{[]byte{0x01, 0x02}, nil, 0},
+		{nil, []byte{0x01}, 0},
+		{[]byte{0x01, 0x02, 0x03}, []byte{0x01, 0x02}, 0},
+		{[]byte{0x01, 0x02}, []byte{0x01, 0x02, 0x03}, 0},
+		{[]byte{0x01, 0x02}, []byte{0x01, 0x02}, 0},
+		{[]byte{0x01, 0x01, 0xff}, []byte{0x01, 0x02}, -1},
+		{[]byte{0x01, 0x03, 0x00}, []byte{0x01, 0x02}, 1},
+		{[]byte{0x00}, []byte{0x01, 0x02}, -1},
+		{[]byte{0xff}, []byte{0x01, 0x02}, 1},
+	}
+
+	for i, test := range tests {
+		if got := ComparePrefix(test.a, test.b); got != test.want {
+			t.Errorf("ComparePrefix #%d (%x, %x): got %d, want %d", i,
+				test.a, test.b, got, test.want)
+		}
+	}
+
+	// Keys of hashes with a prefix must all compare equal to it.
+	hash := mainNetGenesisHash
+	prefix := hash[:3]
+	if !hash.HasPrefix(prefix) || ComparePrefix(hash[:], prefix) != 0 {
+		t.Fatalf("ComparePrefix: hash does not match its prefix")
+	}
+}
+
+// TestHashReverse ensures Reverse returns the hash in reverse byte order
+// without modifying it and that ReverseBytes reverses slices in place.
+func TestHashReverse(t *testing.T) {
+	hash := mainNetGenesisHash
+	reversed := hash.Reverse()
+	if hash != mainNetGenesisHash {
+		t.Fatalf("Reverse: hash modified - got %v, want %v", hash,
+			mainNetGenesisHash)
+	}
+
+	// The hex encoding of the reversed bytes is the displayed form of the
+	// original hash.
+	wantStr := "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	if got := hex.EncodeToString(reversed[:]); got != wantStr {
+		t.Errorf("Reverse: got %v, want %v", got, wantStr)
+	}
+	if got := reversed.String(); got != hex.EncodeToString(hash[:]) {
+		t.Errorf("Reverse: reversed hash string got %v, want %x", got,
+			hash[:])
+	}
+
+	// Reversing twice is the identity.
+	if twice := reversed.Reverse(); twice != hash {
+		t.Errorf("Reverse: reversing twice got %v, want %v", twice,
+			hash)
+	}
+
+	tests := []struct {
+		in   []byte
+		want []byte
+	}{
+		{nil, nil},
+		{[]byte{}, []byte{}},
+		{[]byte{0x01}, []byte{0x01}},
+		{[]byte{0x01, 0x02}, []byte{0x02, 0x01}},
+		{[]byte{0x01, 0x02, 0x03}, []byte{0x03, 0x02, 0x01}},
+		{[]byte{0x01, 0x02, 0x03, 0x04}, []byte{0x04, 0x03, 0x02, 0x01}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		b := append([]byte(nil), test.in...)
+		ReverseBytes(b)
+		if !bytes.Equal(b, test.want) {
+			t.Errorf("ReverseBytes #%d: got %x, want %x", i, b,
+				test.want)
+			continue
+		}
+		ReverseBytes(b)
+		if !bytes.Equal(b, test.in) {
+			t.Errorf("ReverseBytes #%d: reversing twice got %x, "+
+				"want %x", i, b, test.in)
+		}
+	}
+}
+
 // TestNewHashFromStr executes tests against the NewHashFromStr function.
 func TestNewHashFromStr(t *testing.T) {
 	tests := []struct {
@@ -172,7 +552,14 @@ func TestNewHashFromStr(t *testing.T) {
 		{
 			"abcdefg",
 			Hash{},
-			hex.InvalidByteError('g'),
+			ErrHashStrInvalid,
+		},
+
+		// Hash string with an odd length that contains non-hex chars.
+		{
+			"0x1",
+			Hash{},
+			ErrHashStrInvalid,
 		},
 	}
 
@@ -194,3 +581,151 @@ func TestNewHashFromStr(t *testing.T) {
 		}
 	}
 }
+
+// TestNewHashFromStrStrict executes tests against the NewHashFromStrStrict
+// function to ensure only full length hex strings are accepted.
+func TestNewHashFromStrStrict(t *testing.T) {
+	const genesisStr = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	tests := []struct {
+		in   string
+		want Hash
+		err  error
+	}{
+		// Genesis hash.
+		{genesisStr, mainNetGenesisHash, nil},
+
+		// Upper case hex digits.
+		{"000000000019D6689C085AE165831E934FF763AE46A2A6C172B3F1B60A8CE26F",
+			mainNetGenesisHash, nil},
+
+		// 63 characters, which NewHashFromStr would pad.
+		{genesisStr[1:], Hash{}, ErrHashStrSize},
+
+		// 65 characters.
+		{genesisStr + "0", Hash{}, ErrHashStrSize},
+
+		// Stripped leading zeros.
+		{"19d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f",
+			Hash{}, ErrHashStrSize},
+
+		// Empty string.
+		{"", Hash{}, ErrHashStrSize},
+
+		// Non-hex characters.
+		{genesisStr[:63] + "g", Hash{}, ErrHashStrInvalid},
+		{"0x" + genesisStr[2:], Hash{}, ErrHashStrInvalid},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result, err := NewHashFromStrStrict(test.in)
+		if err != test.err {
+			t.Errorf("NewHashFromStrStrict #%d (%q) got error: %v "+
+				"want: %v", i, test.in, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !test.want.IsEqual(result) {
+			t.Errorf("NewHashFromStrStrict #%d got: %v want: %v", i,
+				result, &test.want)
+			continue
+		}
+	}
+}
+
+// TestNewHashFromWire ensures NewHashFromWire keeps the passed bytes in their
+// order while NewHashFromStrStrict reverses the display form, so the same hex
+// string decodes to byte-reversed hashes, and that both validate the length.
+func TestNewHashFromWire(t *testing.T) {
+	const genesisStr = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	raw, err := hex.DecodeString(genesisStr)
+	if err != nil {
+		t.Fatalf("unable to decode hash string: %v", err)
+	}
+
+	wireHash, err := NewHashFromWire(raw)
+	if err != nil {
+		t.Fatalf("NewHashFromWire: unexpected error: %v", err)
+	}
+	if !bytes.Equal(wireHash[:], raw) {
+		t.Fatalf("NewHashFromWire: got %x, want %x", wireHash[:], raw)
+	}
+	displayHash, err := NewHashFromStrStrict(genesisStr)
+	if err != nil {
+		t.Fatalf("NewHashFromStrStrict: unexpected error: %v", err)
+	}
+	if *displayHash != mainNetGenesisHash {
+		t.Fatalf("NewHashFromStrStrict: got %v, want %v", displayHash,
+			mainNetGenesisHash)
+	}
+	if wireHash.Reverse() != *displayHash {
+		t.Fatalf("NewHashFromWire: %v is not the reverse of %v",
+			wireHash, displayHash)
+	}
+
+	// Both reject the wrong number of bytes.
+	for _, n := range []int{0, HashSize - 1, HashSize + 1} {
+		b := make([]byte, n)
+		if _, err := NewHashFromWire(b); err == nil {
+			t.Errorf("NewHashFromWire: %d bytes were accepted", n)
+		}
+		_, err := NewHashFromStrStrict(hex.EncodeToString(b))
+		if err != ErrHashStrSize {
+			t.Errorf("NewHashFromStrStrict: got error %v for %d "+
+				"bytes, want %v", err, n, ErrHashStrSize)
+		}
+	}
+}
+
+// TestHashJSON ensures hashes round trip through encoding/json as the
+// byte-reversed hex string and that malformed strings are rejected.
+func TestHashJSON(t *testing.T) {
+	type block struct {
+		Hash Hash  `json:"hash"`
+		Prev *Hash `json:"prev"`
+	}
+
+	const genesisStr = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+	prev := Hash{0x01}
+	want := `{"hash":"` + genesisStr + `","prev":"` + prev.String() + `"}`
+
+	encoded, err := json.Marshal(block{mainNetGenesisHash, &prev})
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	if string(encoded) != want {
+		t.Fatalf("Marshal\n got: %s want: %s", encoded, want)
+	}
+
+	var result block
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	if result.Hash != mainNetGenesisHash || result.Prev == nil ||
+		*result.Prev != prev {
+
+		t.Fatalf("Unmarshal got: %v, %v want: %v, %v", result.Hash,
+			result.Prev, mainNetGenesisHash, prev)
+	}
+
+	// Only strings of exactly MaxHashStringSize hex characters may be
+	// unmarshalled.
+	tests := []string{
+		`""`,
+		`"19d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"`,
+		`"` + genesisStr[1:] + `"`,
+		`"` + genesisStr + `0"`,
+		`"` + genesisStr[:63] + `g"`,
+		`0`,
+	}
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		var hash Hash
+		if err := json.Unmarshal([]byte(test), &hash); err == nil {
+			t.Errorf("Unmarshal #%d (%s) unexpectedly succeeded", i,
+				test)
+		}
+	}
+}
@@ -5,8 +5,20 @@
 
 package chainhash
 
-import "crypto/sha256"
-import "github.com/aguycalled/gox13hash"
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"sync"
+
+	"github.com/aguycalled/gox13hash"
+	"github.com/dchest/blake256"
+	"gitlab.com/nitya-sattva/go-x11"
+	"golang.org/x/crypto/sha3"
+)
 
 // HashB calculates hash(b) and returns the resulting bytes.
 func HashB(b []byte) []byte {
@@ -33,13 +45,226 @@ func DoubleHashH(b []byte) Hash {
 	return Hash(sha256.Sum256(first[:]))
 }
 
-// X13HashB calculates X13Hash(b) and returns the resulting bytes.
-func X13HashB(b []byte) []byte {
+// MessageChecksum returns the checksum of the passed network message payload,
+// which is the first 4 bytes of hash(hash(payload)).  It is used both when
+// encoding the header of a message and when verifying the payload of a decoded
+// one.
+func MessageChecksum(payload []byte) [4]byte {
+	var checksum [4]byte
+	copy(checksum[:], DoubleHashB(payload)[:4])
+	return checksum
+}
+
+// DoubleHashInto calculates hash(hash(b)) and writes the resulting bytes to
+// dst.  Unlike DoubleHashB, no memory is allocated for the result, which allows
+// callers that compute many hashes, such as when building merkle trees, to
+// reuse the same storage.
+func DoubleHashInto(dst *[HashSize]byte, b []byte) {
+	first := sha256.Sum256(b)
+	*dst = sha256.Sum256(first[:])
+}
+
+// HashReader calculates hash(b), where b is all of the data read from r until
+// EOF, and returns the resulting bytes as a Hash.  The data is streamed through
+// the hash using a fixed size buffer so it never needs to be held in memory in
+// its entirety.  Any error other than EOF encountered while reading from r is
+// returned as is.
+func HashReader(r io.Reader) (Hash, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return Hash{}, err
+	}
+
+	var hash Hash
+	h.Sum(hash[:0])
+	return hash, nil
+}
+
+// DoubleHashReader calculates hash(hash(b)), where b is all of the data read
+// from r until EOF, and returns the resulting bytes as a Hash.  See HashReader
+// for details.
+func DoubleHashReader(r io.Reader) (Hash, error) {
+	first, err := HashReader(r)
+	if err != nil {
+		return Hash{}, err
+	}
+	return HashH(first[:]), nil
+}
+
+// HashMerkleBranches takes two hashes, treated as the left and right tree
+// nodes, and returns the hash of their concatenation, hash(hash(left || right)).
+// This is a helper function used to aid in the generation of a merkle tree.
+func HashMerkleBranches(left, right *Hash) *Hash {
+	// Concatenate the left and right nodes.
+	var buf [HashSize * 2]byte
+	copy(buf[:HashSize], left[:])
+	copy(buf[HashSize:], right[:])
+
+	newHash := DoubleHashH(buf[:])
+	return &newHash
+}
+
+// minParallelBatchSize is the minimum number of inputs DoubleHashBatch hashes
+// in parallel.  Smaller batches are hashed serially since the overhead of
+// spawning goroutines outweighs the benefit.
+const minParallelBatchSize = 64
+
+// DoubleHashBatch calculates hash(hash(b)) for each of the passed inputs and
+// returns the resulting hashes in the same order as the inputs.  Large batches,
+// such as all of the transactions of a block, are split across a number of
+// goroutines matching GOMAXPROCS.  An empty slice is returned when there are no
+// inputs.  The results can be passed as the leaves to CalcMerkleRoot.
+func DoubleHashBatch(inputs [][]byte) []Hash {
+	hashes := make([]Hash, len(inputs))
+	workers := runtime.GOMAXPROCS(0)
+	if len(inputs) < minParallelBatchSize || workers < 2 {
+		for i, b := range inputs {
+			hashes[i] = DoubleHashH(b)
+		}
+		return hashes
+	}
+
+	// Split the inputs into contiguous chunks, one per worker, so that
+	// each worker writes to a distinct range of the results.
+	chunkSize := (len(inputs) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(inputs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(inputs) {
+			end = len(inputs)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			for i := start; i < end; i++ {
+				hashes[i] = DoubleHashH(inputs[i])
+			}
+			wg.Done()
+		}(start, end)
+	}
+	wg.Wait()
+
+	return hashes
+}
+
+// x13Sum calculates X13Hash(b) using the underlying X13 implementation.  It is
+// a variable so tests can replace the implementation with one that misbehaves.
+var x13Sum = func(b []byte) []byte {
 	hash := gox13hash.Sum(b)
 	return hash[:]
 }
 
-// X13HashH calculates X13Hash(b) and returns the resulting bytes as a Hash.
+// X13HashB calculates X13Hash(b) and returns the resulting bytes.
+func X13HashB(b []byte) []byte {
+	return x13Sum(b)
+}
+
+// X13HashH calculates X13Hash(b) and returns the resulting bytes as a Hash.  It
+// never panics.  In the event the underlying X13 implementation returns a result
+// which is not HashSize bytes, the zero hash is returned.  Since the zero hash
+// meets every proof of work target, code which validates proof of work must use
+// X13HashHChecked instead so such a failure is detected.
 func X13HashH(b []byte) Hash {
-	return Hash(gox13hash.Sum(b))
+	hash, _ := X13HashHChecked(b)
+	return hash
+}
+
+// X13HashHChecked calculates X13Hash(b) and returns the resulting bytes as a
+// Hash like X13HashH, except that an error is returned along with the zero hash
+// when the underlying X13 implementation returns a result which is not HashSize
+// bytes.  This guards against a regression in the dependency being silently
+// truncated or causing a panic.
+func X13HashHChecked(b []byte) (Hash, error) {
+	sum := x13Sum(b)
+	if len(sum) != HashSize {
+		return Hash{}, fmt.Errorf("X13 hash has length %d, want %d",
+			len(sum), HashSize)
+	}
+
+	var hash Hash
+	copy(hash[:], sum)
+	return hash, nil
+}
+
+// X13HashReader calculates X13Hash(b), where b is all of the data read from r
+// until EOF, and returns the resulting bytes as a Hash.  The underlying X13
+// implementation only hashes complete inputs, so unlike HashReader the data is
+// buffered in memory before it is hashed.  Any error other than EOF encountered
+// while reading from r is returned as is.
+func X13HashReader(r io.Reader) (Hash, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Hash{}, err
+	}
+	return X13HashH(b), nil
+}
+
+// X11HashB calculates X11Hash(b) and returns the resulting bytes.
+func X11HashB(b []byte) []byte {
+	hash := X11HashH(b)
+	return hash[:]
+}
+
+// X11HashH calculates X11Hash(b) and returns the resulting bytes as a Hash.  A
+// new X11 state is used for every call since the underlying implementation is
+// not safe for concurrent use.
+func X11HashH(b []byte) Hash {
+	var hash Hash
+	x11.New().Hash(b, hash[:])
+	return hash
+}
+
+// Blake256B calculates Blake256(b) and returns the resulting bytes.
+func Blake256B(b []byte) []byte {
+	h := blake256.New()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// Blake256H calculates Blake256(b) and returns the resulting bytes as a Hash.
+func Blake256H(b []byte) Hash {
+	var hash Hash
+	h := blake256.New()
+	h.Write(b)
+	h.Sum(hash[:0])
+	return hash
+}
+
+// Keccak256B calculates Keccak256(b) and returns the resulting bytes.  This is
+// the original Keccak submission as used by Ethereum, which differs from the
+// standardized SHA3-256 in its padding.
+func Keccak256B(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// Keccak256H calculates Keccak256(b) and returns the resulting bytes as a Hash.
+// See Keccak256B for details.
+func Keccak256H(b []byte) Hash {
+	var hash Hash
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	h.Sum(hash[:0])
+	return hash
+}
+
+// HMAC256 calculates HMAC-SHA256(key, data) and returns the resulting bytes.
+//
+// NOTE: The result is a message authentication tag for the data under the key,
+// such as those used to authenticate an encrypted peer connection, rather than
+// a block or transaction hash.
+func HMAC256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+// HMAC256H calculates HMAC-SHA256(key, data) and returns the resulting bytes as
+// a Hash.  See HMAC256 for details.
+func HMAC256H(key, data []byte) Hash {
+	var hash Hash
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	h.Sum(hash[:0])
+	return hash
 }
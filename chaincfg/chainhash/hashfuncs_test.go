@@ -5,7 +5,12 @@
 package chainhash
 
 import (
+	"bytes"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"strings"
 	"testing"
 )
 
@@ -133,4 +138,428 @@ func TestDoubleHashFuncs(t *testing.T) {
 			continue
 		}
 	}
+
+	// Ensure the hash function which writes into a caller provided array
+	// returns the expected result, including when the array is reused.
+	var dst [HashSize]byte
+	for _, test := range tests {
+		DoubleHashInto(&dst, []byte(test.in))
+		if !bytes.Equal(dst[:], DoubleHashB([]byte(test.in))) {
+			t.Errorf("DoubleHashInto(%q) = %x, want %s", test.in,
+				dst[:], test.out)
+			continue
+		}
+	}
+}
+
+// errReader is an io.Reader which returns some data followed by an error.
+type errReader struct {
+	data []byte
+	err  error
+}
+
+// Read returns the remaining data of the reader followed by its error.
+func (r *errReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// TestHashReaderFuncs ensures the hash functions which stream their input from
+// an io.Reader produce the same results as their in-memory counterparts and
+// return read errors unmodified.
+func TestHashReaderFuncs(t *testing.T) {
+	// Include sizes on both sides of the internal copy buffer size.
+	for _, size := range []int{0, 1, 64, 1000, 32*1024 - 1, 32 * 1024,
+		32*1024 + 1, 100000} {
+
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+
+		hash, err := HashReader(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("HashReader (size %d): unexpected error: %v",
+				size, err)
+			continue
+		}
+		if want := HashH(data); hash != want {
+			t.Errorf("HashReader (size %d) = %v, want %v", size,
+				hash, want)
+			continue
+		}
+
+		hash, err = DoubleHashReader(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("DoubleHashReader (size %d): unexpected "+
+				"error: %v", size, err)
+			continue
+		}
+		if want := DoubleHashH(data); hash != want {
+			t.Errorf("DoubleHashReader (size %d) = %v, want %v",
+				size, hash, want)
+			continue
+		}
+
+		hash, err = X13HashReader(bytes.NewReader(data))
+		if err != nil {
+			t.Errorf("X13HashReader (size %d): unexpected error: "+
+				"%v", size, err)
+			continue
+		}
+		if want := X13HashH(data); hash != want {
+			t.Errorf("X13HashReader (size %d) = %v, want %v", size,
+				hash, want)
+			continue
+		}
+	}
+
+	// Ensure read errors are returned unmodified.
+	readErr := errors.New("read failure")
+	funcs := []func(io.Reader) (Hash, error){HashReader, DoubleHashReader,
+		X13HashReader}
+	for i, f := range funcs {
+		_, err := f(&errReader{data: []byte("abc"), err: readErr})
+		if err != readErr {
+			t.Errorf("reader func #%d: got error %v, want %v", i,
+				err, readErr)
+		}
+	}
+}
+
+// TestX13HashHChecked ensures X13HashHChecked returns the X13 hash of its input
+// and that a misbehaving X13 implementation is detected without panicking or
+// allowing proof of work to be met.
+func TestX13HashHChecked(t *testing.T) {
+	data := []byte("x13 checked input")
+	hash, err := X13HashHChecked(data)
+	if err != nil {
+		t.Fatalf("X13HashHChecked: unexpected error: %v", err)
+	}
+	if !bytes.Equal(hash[:], X13HashB(data)) || hash != X13HashH(data) {
+		t.Fatalf("X13HashHChecked = %v, want %x", hash, X13HashB(data))
+	}
+
+	origSum := x13Sum
+	defer func() { x13Sum = origSum }()
+	for _, size := range []int{0, HashSize - 1, HashSize + 1} {
+		x13Sum = func(b []byte) []byte {
+			return bytes.Repeat([]byte{0xff}, size)
+		}
+
+		hash, err := X13HashHChecked(data)
+		if err == nil || hash != (Hash{}) {
+			t.Errorf("X13HashHChecked (size %d) = %v, %v, want zero "+
+				"hash and error", size, hash, err)
+		}
+		if hash := X13HashH(data); hash != (Hash{}) {
+			t.Errorf("X13HashH (size %d) = %v, want zero hash", size,
+				hash)
+		}
+		if CheckProofOfWorkX13(data, 0x207fffff) {
+			t.Errorf("CheckProofOfWorkX13 (size %d): target met", size)
+		}
+		if hash := X13HashHCached(data); hash != (Hash{}) {
+			t.Errorf("X13HashHCached (size %d) = %v, want zero hash",
+				size, hash)
+		}
+	}
+
+	// The failures must not have been memoized.
+	x13Sum = origSum
+	if hash := X13HashHCached(data); hash != X13HashH(data) {
+		t.Fatalf("X13HashHCached = %v, want %v", hash, X13HashH(data))
+	}
+}
+
+// TestHashMerkleBranches ensures hashing a pair of merkle tree nodes produces
+// the expected parent node.
+func TestHashMerkleBranches(t *testing.T) {
+	// The two transactions of block 170 of the bitcoin main chain and the
+	// merkle root of the block.
+	tests := []struct {
+		left  string
+		right string
+		want  string
+	}{
+		{
+			left:  "b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+			right: "f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			want:  "7dac2c5666815c17a3b36427de37bb9d2e2c5ccec3f8633eb91a4205cb4c10ff",
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		left, err := NewHashFromStr(test.left)
+		if err != nil {
+			t.Errorf("NewHashFromStr #%d: unexpected error: %v", i, err)
+			continue
+		}
+		right, err := NewHashFromStr(test.right)
+		if err != nil {
+			t.Errorf("NewHashFromStr #%d: unexpected error: %v", i, err)
+			continue
+		}
+
+		result := HashMerkleBranches(left, right)
+		if result.String() != test.want {
+			t.Errorf("HashMerkleBranches #%d\n got: %v want: %v", i,
+				result, test.want)
+			continue
+		}
+	}
+}
+
+// TestX11HashFuncs ensures the hash functions which perform X11Hash(b) work as
+// expected.  The inputs are the serialized genesis block headers of the main
+// and test Dash networks, whose hashes are known X11 digests.
+func TestX11HashFuncs(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		hash   string
+	}{
+		{"dash mainnet genesis", "01000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"c762a6567f3cc092f0684bb62b7e00a84890b990f07cc71a6bb58d64b98e02e0" +
+			"022ddb52f0ff0f1ec23fb901",
+			"00000ffd590b1485b3caadc19b22e6379c733355108f107a430458cdf3407ab6"},
+		{"dash testnet genesis", "01000000" +
+			"0000000000000000000000000000000000000000000000000000000000000000" +
+			"c762a6567f3cc092f0684bb62b7e00a84890b990f07cc71a6bb58d64b98e02e0" +
+			"dee1e352f0ff0f1ec3c927e6",
+			"00000bafbc94add76cb75e2ec92894837288a481e5c005f6563d91623bf8bc2c"},
+	}
+
+	for _, test := range tests {
+		header, err := hex.DecodeString(test.header)
+		if err != nil {
+			t.Fatalf("%s: unable to decode header: %v", test.name, err)
+		}
+		want, err := NewHashFromStr(test.hash)
+		if err != nil {
+			t.Fatalf("%s: unable to decode hash: %v", test.name, err)
+		}
+
+		if got := X11HashH(header); got != *want {
+			t.Errorf("X11HashH (%s) = %v, want %v", test.name, got,
+				want)
+			continue
+		}
+		if got := X11HashB(header); !bytes.Equal(got, want[:]) {
+			t.Errorf("X11HashB (%s) = %x, want %x", test.name, got,
+				want[:])
+			continue
+		}
+	}
+}
+
+// TestBlake256Funcs ensures the hash functions which perform Blake256(b) work
+// as expected.
+func TestBlake256Funcs(t *testing.T) {
+	tests := []struct {
+		out string
+		in  string
+	}{
+		// Test vectors from the BLAKE specification.
+		{"716f6e863f744b9ac22c97ec7b76ea5f5908bc5b2f67c61510bfc4751384ea7a", ""},
+		{"0ce8d4ef4dd7cd8d62dfded9d4edb0a774ae6a41929a74da23109e8f11139c87", "\x00"},
+		{"d419bad32d504fb7d44d460c42c5593fe544fa4c135dec31e21bd9abdcc22d41", strings.Repeat("\x00", 72)},
+		{"1833a9fa7cf4086bd5fda73da32e5a1d75b4c3f89d5c436369f9d78bb2da5c28", "abc"},
+		{"7576698ee9cad30173080678e5965916adbb11cb5245d386bf1ffda1cb26c9d7", "The quick brown fox jumps over the lazy dog"},
+	}
+
+	for _, test := range tests {
+		h := fmt.Sprintf("%x", Blake256B([]byte(test.in)))
+		if h != test.out {
+			t.Errorf("Blake256B(%q) = %s, want %s", test.in, h,
+				test.out)
+			continue
+		}
+
+		hash := Blake256H([]byte(test.in))
+		h = fmt.Sprintf("%x", hash[:])
+		if h != test.out {
+			t.Errorf("Blake256H(%q) = %s, want %s", test.in, h,
+				test.out)
+			continue
+		}
+	}
+}
+
+// TestKeccak256Funcs ensures the hash functions which perform Keccak256(b) work
+// as expected.
+func TestKeccak256Funcs(t *testing.T) {
+	tests := []struct {
+		out string
+		in  string
+	}{
+		{"c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470", ""},
+		{"4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45", "abc"},
+		{"4d741b6f1eb29cb2a9b9911c82f56fa8d73b04959d3d9d222895df6c0b28aa15", "The quick brown fox jumps over the lazy dog"},
+		{"96ea54061def936c4be90b518992fdc6f12f535068a256229aca54267b4d084d", strings.Repeat("a", 200)},
+	}
+
+	for _, test := range tests {
+		h := fmt.Sprintf("%x", Keccak256B([]byte(test.in)))
+		if h != test.out {
+			t.Errorf("Keccak256B(%q) = %s, want %s", test.in, h,
+				test.out)
+			continue
+		}
+
+		hash := Keccak256H([]byte(test.in))
+		h = fmt.Sprintf("%x", hash[:])
+		if h != test.out {
+			t.Errorf("Keccak256H(%q) = %s, want %s", test.in, h,
+				test.out)
+			continue
+		}
+	}
+}
+
+// TestMessageChecksum ensures MessageChecksum returns the expected checksums
+// of network message payloads.
+func TestMessageChecksum(t *testing.T) {
+	tests := []struct {
+		payload []byte
+		want    [4]byte
+	}{
+		// The empty payload of a verack message.
+		{nil, [4]byte{0x5d, 0xf6, 0xe0, 0xe2}},
+		{[]byte("hello"), [4]byte{0x95, 0x95, 0xc9, 0xdf}},
+	}
+
+	for _, test := range tests {
+		checksum := MessageChecksum(test.payload)
+		if checksum != test.want {
+			t.Errorf("MessageChecksum(%q) = %x, want %x",
+				test.payload, checksum, test.want)
+		}
+	}
+}
+
+// TestHMAC256Funcs ensures the hash functions which perform HMAC-SHA256 work as
+// expected using the test vectors from RFC 4231.
+func TestHMAC256Funcs(t *testing.T) {
+	largeKey := strings.Repeat("\xaa", 131)
+	tests := []struct {
+		out  string
+		key  string
+		data string
+	}{
+		{"b0344c61d8db38535ca8afceaf0bf12b881dc200c9833da726e9376c2e32cff7", strings.Repeat("\x0b", 20), "Hi There"},
+		{"5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843", "Jefe", "what do ya want for nothing?"},
+		{"773ea91e36800e46854db8ebd09181a72959098b3ef8c122d9635514ced565fe", strings.Repeat("\xaa", 20), strings.Repeat("\xdd", 50)},
+		{"82558a389a443c0ea4cc819899f2083a85f0faa3e578f8077a2e3ff46729665b", "\x01\x02\x03\x04\x05\x06\x07\x08\x09\x0a\x0b\x0c\x0d\x0e\x0f\x10\x11\x12\x13\x14\x15\x16\x17\x18\x19", strings.Repeat("\xcd", 50)},
+		{"60e431591ee0b67f0d8a26aacbf5b77f8e0bc6213728c5140546040f0ee37f54", largeKey, "Test Using Larger Than Block-Size Key - Hash Key First"},
+		{"9b09ffa71b942fcb27635fbcd5b0e944bfdc63644f0713938a7f51535c3a35e2", largeKey, "This is a test using a larger than block-size key and a larger than block-size data. The key needs to be hashed before being used by the HMAC algorithm."},
+	}
+
+	for _, test := range tests {
+		h := fmt.Sprintf("%x", HMAC256([]byte(test.key), []byte(test.data)))
+		if h != test.out {
+			t.Errorf("HMAC256(%x, %q) = %s, want %s", test.key,
+				test.data, h, test.out)
+			continue
+		}
+
+		hash := HMAC256H([]byte(test.key), []byte(test.data))
+		h = fmt.Sprintf("%x", hash[:])
+		if h != test.out {
+			t.Errorf("HMAC256H(%x, %q) = %s, want %s", test.key,
+				test.data, h, test.out)
+			continue
+		}
+	}
+}
+
+// TestDoubleHashBatch ensures hashing a batch of inputs produces the same
+// results as hashing them individually and preserves the order of the inputs
+// for batches which are hashed both serially and in parallel.
+func TestDoubleHashBatch(t *testing.T) {
+	for _, size := range []int{0, 1, minParallelBatchSize - 1,
+		minParallelBatchSize, 1000} {
+
+		inputs := make([][]byte, size)
+		for i := range inputs {
+			inputs[i] = []byte(fmt.Sprintf("input %d", i))
+		}
+
+		hashes := DoubleHashBatch(inputs)
+		if len(hashes) != size {
+			t.Errorf("DoubleHashBatch (size %d): got %d hashes",
+				size, len(hashes))
+			continue
+		}
+		for i, hash := range hashes {
+			if want := DoubleHashH(inputs[i]); hash != want {
+				t.Errorf("DoubleHashBatch (size %d) #%d = %v, "+
+					"want %v", size, i, hash, want)
+				break
+			}
+		}
+	}
+}
+
+// genBatchInputs returns the requested number of inputs the size of a typical
+// transaction for the batch hashing benchmarks.
+func genBatchInputs(count int) [][]byte {
+	inputs := make([][]byte, count)
+	for i := range inputs {
+		inputs[i] = make([]byte, 250)
+		inputs[i][0] = byte(i)
+		inputs[i][1] = byte(i >> 8)
+	}
+	return inputs
+}
+
+// BenchmarkDoubleHashSerial benchmarks double hashing a block's worth of
+// transactions one at a time.
+func BenchmarkDoubleHashSerial(b *testing.B) {
+	inputs := genBatchInputs(2000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, input := range inputs {
+			DoubleHashH(input)
+		}
+	}
+}
+
+// BenchmarkDoubleHashBatch benchmarks double hashing a block's worth of
+// transactions with DoubleHashBatch.
+func BenchmarkDoubleHashBatch(b *testing.B) {
+	inputs := genBatchInputs(2000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		DoubleHashBatch(inputs)
+	}
+}
+
+// BenchmarkDoubleHashB benchmarks double hashing a typical transaction with
+// DoubleHashB, which allocates the result.
+func BenchmarkDoubleHashB(b *testing.B) {
+	input := genBatchInputs(1)[0]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		DoubleHashB(input)
+	}
+}
+
+// BenchmarkDoubleHashInto benchmarks double hashing a typical transaction with
+// DoubleHashInto, which reuses the caller's storage.
+func BenchmarkDoubleHashInto(b *testing.B) {
+	input := genBatchInputs(1)[0]
+	var dst [HashSize]byte
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		DoubleHashInto(&dst, input)
+	}
 }
@@ -0,0 +1,73 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+// HashSet is a set of hashes with constant time membership tests, such as the
+// hashes of checkpointed or banned blocks.  The zero value is an empty set
+// ready to use.
+//
+// NOTE: A HashSet is not safe for concurrent modification.
+type HashSet struct {
+	hashes map[Hash]struct{}
+}
+
+// NewHashSet returns a new set holding the passed hashes, ignoring duplicates.
+// The hashes are copied, so the slice may be modified afterwards without
+// affecting the set.
+func NewHashSet(hashes []Hash) *HashSet {
+	set := &HashSet{hashes: make(map[Hash]struct{}, len(hashes))}
+	for i := range hashes {
+		set.hashes[hashes[i]] = struct{}{}
+	}
+	return set
+}
+
+// Add adds the passed hash to the set and returns whether it was not already
+// in it.
+func (set *HashSet) Add(hash *Hash) bool {
+	if set.hashes == nil {
+		set.hashes = make(map[Hash]struct{})
+	}
+	if _, ok := set.hashes[*hash]; ok {
+		return false
+	}
+	set.hashes[*hash] = struct{}{}
+	return true
+}
+
+// Contains returns whether the passed hash is in the set.
+func (set *HashSet) Contains(hash *Hash) bool {
+	_, ok := set.hashes[*hash]
+	return ok
+}
+
+// Remove removes the passed hash from the set and returns whether it was in
+// it.
+func (set *HashSet) Remove(hash *Hash) bool {
+	if _, ok := set.hashes[*hash]; !ok {
+		return false
+	}
+	delete(set.hashes, *hash)
+	return true
+}
+
+// Len returns the number of hashes in the set.
+func (set *HashSet) Len() int {
+	return len(set.hashes)
+}
+
+// Union returns a new set holding the hashes which are in either the set or
+// the passed one.  Neither set is modified.
+func (set *HashSet) Union(other *HashSet) *HashSet {
+	union := &HashSet{hashes: make(map[Hash]struct{},
+		len(set.hashes)+len(other.hashes))}
+	for hash := range set.hashes {
+		union.hashes[hash] = struct{}{}
+	}
+	for hash := range other.hashes {
+		union.hashes[hash] = struct{}{}
+	}
+	return union
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import "testing"
+
+// TestHashSet ensures the membership of hashes in a HashSet is tracked as
+// expected, including duplicate additions and removals.
+func TestHashSet(t *testing.T) {
+	hashes := []Hash{{0x01}, {0x02}, {0x01}, {0x03}}
+	set := NewHashSet(hashes)
+	if set.Len() != 3 {
+		t.Fatalf("NewHashSet: set holds %d hashes, want 3", set.Len())
+	}
+
+	// Modifying the slice must not affect the set.
+	hashes[3] = Hash{0x04}
+	if !set.Contains(&Hash{0x03}) || set.Contains(&Hash{0x04}) {
+		t.Fatalf("NewHashSet: set changed with the slice")
+	}
+
+	if set.Add(&Hash{0x02}) {
+		t.Fatalf("Add: duplicate hash was added")
+	}
+	if !set.Add(&Hash{0x04}) || !set.Contains(&Hash{0x04}) {
+		t.Fatalf("Add: new hash was not added")
+	}
+	if set.Len() != 4 {
+		t.Fatalf("Add: set holds %d hashes, want 4", set.Len())
+	}
+
+	if !set.Remove(&Hash{0x01}) || set.Contains(&Hash{0x01}) {
+		t.Fatalf("Remove: hash was not removed")
+	}
+	if set.Remove(&Hash{0x01}) {
+		t.Fatalf("Remove: missing hash was removed")
+	}
+	if set.Len() != 3 {
+		t.Fatalf("Remove: set holds %d hashes, want 3", set.Len())
+	}
+
+	// The zero value must be an empty set ready to use.
+	var zero HashSet
+	if zero.Len() != 0 || zero.Contains(&Hash{}) || zero.Remove(&Hash{}) {
+		t.Fatalf("HashSet: zero value is not empty")
+	}
+	if !zero.Add(&Hash{}) || !zero.Contains(&Hash{}) {
+		t.Fatalf("Add: hash was not added to zero value")
+	}
+}
+
+// TestHashSetUnion ensures the union of two HashSets holds the hashes of both
+// without modifying either.
+func TestHashSetUnion(t *testing.T) {
+	a := NewHashSet([]Hash{{0x01}, {0x02}})
+	b := NewHashSet([]Hash{{0x02}, {0x03}})
+	union := a.Union(b)
+
+	if union.Len() != 3 {
+		t.Fatalf("Union: set holds %d hashes, want 3", union.Len())
+	}
+	for _, hash := range []Hash{{0x01}, {0x02}, {0x03}} {
+		if !union.Contains(&hash) {
+			t.Errorf("Union: missing hash %v", hash)
+		}
+	}
+	if a.Len() != 2 || b.Len() != 2 || a.Contains(&Hash{0x03}) {
+		t.Fatalf("Union: operands were modified")
+	}
+
+	// The union must not share storage with its operands.
+	union.Add(&Hash{0x04})
+	if a.Contains(&Hash{0x04}) || b.Contains(&Hash{0x04}) {
+		t.Fatalf("Union: result shares storage with operands")
+	}
+
+	// Unions with empty sets hold the hashes of the other set.
+	var empty HashSet
+	if n := empty.Union(a).Len(); n != 2 {
+		t.Fatalf("Union: union with empty set holds %d hashes, want 2", n)
+	}
+	if n := a.Union(&empty).Len(); n != 2 {
+		t.Fatalf("Union: union with empty set holds %d hashes, want 2", n)
+	}
+}
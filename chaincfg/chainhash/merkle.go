@@ -0,0 +1,58 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+// CalcMerkleRoot calculates the merkle root of a tree with the passed leaves,
+// such as the hashes of the transactions of a block in order.  Each level of
+// the tree is built by hashing adjacent pairs of nodes with HashMerkleBranches,
+// and when a level has an odd number of nodes, the last one is paired with
+// itself.  The root of a single leaf is the leaf itself and the root of no
+// leaves is the zero hash.  The leaves of a block can be calculated from the
+// serializations of its transactions with DoubleHashBatch.
+//
+// The passed slice is not modified.
+func CalcMerkleRoot(leaves []Hash) Hash {
+	switch len(leaves) {
+	case 0:
+		return Hash{}
+	case 1:
+		return leaves[0]
+	}
+
+	// Build each level in place over a copy of the leaves, since every
+	// parent is stored at or before the position of its left child.
+	level := make([]Hash, len(leaves))
+	copy(level, leaves)
+	for len(level) > 1 {
+		for i := 0; i < len(level); i += 2 {
+			right := i + 1
+			if right == len(level) {
+				right = i
+			}
+			level[i/2] = *HashMerkleBranches(&level[i], &level[right])
+		}
+		level = level[:(len(level)+1)/2]
+	}
+	return level[0]
+}
+
+// CalcWitnessMerkleRoot calculates the witness merkle root committed to by the
+// coinbase of a block from the witness hashes of its transactions in order.  It
+// is built exactly like CalcMerkleRoot, except that the first hash, which
+// belongs to the coinbase, is always treated as the zero hash regardless of its
+// value.  The coinbase can't commit to its own witness hash since the
+// commitment is part of it, so the consensus rules define it as all zeroes.
+// The witness merkle root of no hashes is the zero hash.
+//
+// The passed slice is not modified.
+func CalcWitnessMerkleRoot(witnessHashes []Hash) Hash {
+	if len(witnessHashes) == 0 {
+		return Hash{}
+	}
+
+	leaves := make([]Hash, len(witnessHashes))
+	copy(leaves[1:], witnessHashes[1:])
+	return CalcMerkleRoot(leaves)
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import "testing"
+
+// block100000Hashes are the hashes of the transactions in block 100000 of the
+// bitcoin main network, whose merkle root is
+// f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766.
+var block100000Hashes = []string{
+	"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+	"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+	"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+	"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+}
+
+// TestCalcMerkleRoot ensures CalcMerkleRoot returns the expected merkle roots,
+// including for trees with an odd number of nodes at some level.
+func TestCalcMerkleRoot(t *testing.T) {
+	tests := []struct {
+		name   string
+		leaves []string
+		want   string
+	}{
+		{
+			name:   "no leaves",
+			leaves: nil,
+			want:   "0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		{
+			// Block 1 of the bitcoin main network.
+			name: "1 tx",
+			leaves: []string{
+				"0e3e2357e806b6cdb1f70b54c3a3a17b6714ee1f0e68bebb44a74b1efd512098",
+			},
+			want: "0e3e2357e806b6cdb1f70b54c3a3a17b6714ee1f0e68bebb44a74b1efd512098",
+		},
+		{
+			// Block 170 of the bitcoin main network.
+			name: "2 tx",
+			leaves: []string{
+				"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+				"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+			},
+			want: "7dac2c5666815c17a3b36427de37bb9d2e2c5ccec3f8633eb91a4205cb4c10ff",
+		},
+		{
+			// The first three transactions of block 100000 of the
+			// bitcoin main network, which duplicates the third.
+			name:   "3 tx",
+			leaves: block100000Hashes[:3],
+			want:   "fa435470825de273081dcc706b25514c936fa6dc80ab965ce6970d68ddd0b553",
+		},
+		{
+			// Block 100000 of the bitcoin main network.
+			name:   "4 tx",
+			leaves: block100000Hashes,
+			want:   "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+		},
+		{
+			// Block 100000 with its first transaction repeated at the
+			// end, which duplicates nodes at two levels.
+			name:   "5 tx",
+			leaves: append(block100000Hashes[:4:4], block100000Hashes[0]),
+			want:   "294b257084a14ef954334f28cffb6f7724e27b025bfc8111ed89a503184eb42f",
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		leaves := make([]Hash, len(test.leaves))
+		for i, leaf := range test.leaves {
+			hash, err := NewHashFromStr(leaf)
+			if err != nil {
+				t.Fatalf("NewHashFromStr: %v", err)
+			}
+			leaves[i] = *hash
+		}
+		orig := make([]Hash, len(leaves))
+		copy(orig, leaves)
+
+		root := CalcMerkleRoot(leaves)
+		if root.String() != test.want {
+			t.Errorf("CalcMerkleRoot (%s): got %v, want %v", test.name,
+				root, test.want)
+		}
+		for i := range leaves {
+			if leaves[i] != orig[i] {
+				t.Errorf("CalcMerkleRoot (%s): leaf %d was modified",
+					test.name, i)
+			}
+		}
+	}
+}
+
+// TestCalcWitnessMerkleRoot ensures CalcWitnessMerkleRoot treats the witness
+// hash of the coinbase as the zero hash and duplicates the last node of levels
+// with an odd number of nodes.
+func TestCalcWitnessMerkleRoot(t *testing.T) {
+	tests := []struct {
+		name   string
+		hashes []string
+		want   string
+	}{
+		{
+			name:   "no hashes",
+			hashes: nil,
+			want:   "0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		{
+			name:   "coinbase only",
+			hashes: block100000Hashes[:1],
+			want:   "0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		{
+			name:   "2 tx",
+			hashes: block100000Hashes[:2],
+			want:   "a4f10667c2f83b909d7e1a3bb979fe3dd34f25ea26b4fe4ca99eaccdaaf0ba0d",
+		},
+		{
+			name:   "3 tx",
+			hashes: block100000Hashes[:3],
+			want:   "a7f70d7c4958e3e4130ae3405489356b87695d40328eb2482355ee087a12b29b",
+		},
+		{
+			name:   "4 tx",
+			hashes: block100000Hashes,
+			want:   "e9b915f49bde65e53f1ca83d0d7589d613362edb0ac0ceeff5b348fe111e8a0e",
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		hashes := make([]Hash, len(test.hashes))
+		for i, h := range test.hashes {
+			hash, err := NewHashFromStr(h)
+			if err != nil {
+				t.Fatalf("NewHashFromStr: %v", err)
+			}
+			hashes[i] = *hash
+		}
+
+		root := CalcWitnessMerkleRoot(hashes)
+		if root.String() != test.want {
+			t.Errorf("CalcWitnessMerkleRoot (%s): got %v, want %v",
+				test.name, root, test.want)
+		}
+		if len(hashes) == 0 {
+			continue
+		}
+
+		// The witness hash of the coinbase must not affect the root or
+		// be modified.
+		hashes[0] = Hash{0x01}
+		if got := CalcWitnessMerkleRoot(hashes); got != root {
+			t.Errorf("CalcWitnessMerkleRoot (%s): coinbase witness "+
+				"hash changed the root to %v", test.name, got)
+		}
+		if hashes[0] != (Hash{0x01}) {
+			t.Errorf("CalcWitnessMerkleRoot (%s): coinbase witness "+
+				"hash was modified", test.name)
+		}
+
+		// The root must match the merkle root over the same hashes with
+		// a zero coinbase hash.
+		hashes[0] = Hash{}
+		if got := CalcMerkleRoot(hashes); got != root {
+			t.Errorf("CalcWitnessMerkleRoot (%s): got %v, want merkle "+
+				"root %v", test.name, root, got)
+		}
+	}
+}
+
+// TestMerkleRootFromSerializations ensures the leaves returned by
+// DoubleHashBatch for the serializations of transactions are the double hashes
+// of the serializations in order and produce the expected merkle root.
+func TestMerkleRootFromSerializations(t *testing.T) {
+	serializations := [][]byte{
+		[]byte("tx one"),
+		[]byte("tx two"),
+		[]byte("tx three"),
+	}
+	wantLeaves := []string{
+		"2ed75f46b3876f78fda7fb1c1dd9f9d38ab3827641bb7c3883633fb2d7839508",
+		"811c0ab28f5ac6d44a7136076c545c7284e69b120efd4f37494354515d31a32d",
+		"ccbd539719667c9005142ca1058e05a4b1414525c7718c8b019ebeb09168b0ce",
+	}
+	const wantRoot = "cd15d62e5b2f3d6d7c4e98d696a3c7645913dcc24dec3a692b133284bad4c60e"
+
+	leaves := DoubleHashBatch(serializations)
+	if len(leaves) != len(wantLeaves) {
+		t.Fatalf("DoubleHashBatch: got %d leaves, want %d", len(leaves),
+			len(wantLeaves))
+	}
+	for i, leaf := range leaves {
+		if leaf.String() != wantLeaves[i] {
+			t.Errorf("DoubleHashBatch: leaf %d is %v, want %v", i, leaf,
+				wantLeaves[i])
+		}
+	}
+	if root := CalcMerkleRoot(leaves); root.String() != wantRoot {
+		t.Errorf("CalcMerkleRoot: got %v, want %v", root, wantRoot)
+	}
+
+	// No serializations must result in an empty, rather than nil, slice of
+	// leaves.
+	leaves = DoubleHashBatch(nil)
+	if leaves == nil || len(leaves) != 0 {
+		t.Errorf("DoubleHashBatch: got %#v for no inputs, want empty "+
+			"slice", leaves)
+	}
+}
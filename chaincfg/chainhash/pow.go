@@ -0,0 +1,225 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import (
+	"encoding/binary"
+	"math/big"
+	"time"
+)
+
+// CompactToBig converts a compact representation of a whole number N, such as
+// the target difficulty bits of a block header, to a big.Int.  The
+// representation is similar to IEEE754 floating
+// point numbers.
+//
+// Like IEEE754 floating point, there are three basic components: the sign,
+// the exponent, and the mantissa.  They are broken out as follows:
+//
+//	* the most significant 8 bits represent the unsigned base 256 exponent
+//	* bit 23 (the 24th bit) represents the sign bit
+//	* the least significant 23 bits represent the mantissa
+//
+//	-------------------------------------------------
+//	|   Exponent     |    Sign    |    Mantissa     |
+//	-------------------------------------------------
+//	| 8 bits [31-24] | 1 bit [23] | 23 bits [22-00] |
+//	-------------------------------------------------
+//
+// The formula to calculate N is:
+//
+//	N = (-1^sign) * mantissa * 256^(exponent-3)
+//
+// This compact form is only used in navcoin to encode unsigned 256-bit numbers
+// which represent difficulty targets, thus there really is not a need for a
+// sign bit, but it is implemented here to stay consistent with navcoind.
+func CompactToBig(compact uint32) *big.Int {
+	// Extract the mantissa, sign bit, and exponent.
+	mantissa := compact & 0x007fffff
+	isNegative := compact&0x00800000 != 0
+	exponent := uint(compact >> 24)
+
+	// Since the base for the exponent is 256, the exponent can be treated
+	// as the number of bytes to represent the full 256-bit number.  So,
+	// treat the exponent as the number of bytes and shift the mantissa
+	// right or left accordingly.  This is equivalent to:
+	// N = mantissa * 256^(exponent-3)
+	var bn *big.Int
+	if exponent <= 3 {
+		mantissa >>= 8 * (3 - exponent)
+		bn = big.NewInt(int64(mantissa))
+	} else {
+		bn = big.NewInt(int64(mantissa))
+		bn.Lsh(bn, 8*(exponent-3))
+	}
+
+	// Make it negative if the sign bit is set.
+	if isNegative {
+		bn = bn.Neg(bn)
+	}
+
+	return bn
+}
+
+// BigToCompact converts a whole number N to a compact representation using
+// an unsigned 32-bit number.  The compact representation only provides 23 bits
+// of precision, so values larger than (2^23 - 1) only encode the most
+// significant digits of the number.  See CompactToBig for details.
+func BigToCompact(n *big.Int) uint32 {
+	// No need to do any work if it's zero.
+	if n.Sign() == 0 {
+		return 0
+	}
+
+	// Since the base for the exponent is 256, the exponent can be treated
+	// as the number of bytes.  So, shift the number right or left
+	// accordingly.  This is equivalent to:
+	// mantissa = mantissa / 256^(exponent-3)
+	var mantissa uint32
+	exponent := uint(len(n.Bytes()))
+	if exponent <= 3 {
+		mantissa = uint32(n.Bits()[0])
+		mantissa <<= 8 * (3 - exponent)
+	} else {
+		// Use a copy to avoid modifying the caller's original number.
+		tn := new(big.Int).Set(n)
+		mantissa = uint32(tn.Rsh(tn, 8*(exponent-3)).Bits()[0])
+	}
+
+	// When the mantissa already has the sign bit set, the number is too
+	// large to fit into the available 23-bits, so divide the number by 256
+	// and increment the exponent accordingly.
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	// Pack the exponent, sign bit, and mantissa into an unsigned 32-bit
+	// int and return it.
+	compact := uint32(exponent<<24) | mantissa
+	if n.Sign() < 0 {
+		compact |= 0x00800000
+	}
+	return compact
+}
+
+// CheckProofOfWork returns whether the passed proof of work hash meets the
+// target difficulty represented by the passed compact bits.  The hash is
+// interpreted as a little-endian 256-bit number, as is done for block hashes
+// throughout, before comparing it against the target.  Targets which are zero
+// or negative are never met.
+func CheckProofOfWork(hash *Hash, targetBits uint32) bool {
+	target := CompactToBig(targetBits)
+	if target.Sign() <= 0 {
+		return false
+	}
+
+	return hash.ToBig().Cmp(target) <= 0
+}
+
+// CheckProofOfWorkX13 returns whether the X13 hash of the passed serialized
+// block header is less than or equal to the target difficulty represented by
+// the passed compact bits.  The hash is interpreted as a little-endian 256-bit
+// number, as is done for block hashes throughout, before comparing it against
+// the target.  Targets which are zero or negative are never met.
+//
+// The target is never met when the X13 hash can't be calculated.
+//
+// Note that this only checks the hash against the target.  The caller is
+// responsible for ensuring the target itself is within the range allowed by
+// the network.
+func CheckProofOfWorkX13(headerBytes []byte, targetBits uint32) bool {
+	hash, err := X13HashHChecked(headerBytes)
+	if err != nil {
+		return false
+	}
+	return CheckProofOfWork(&hash, targetBits)
+}
+
+// doubleHashBlockVersion is the first block version whose block hash is the
+// double SHA-256 of its header rather than its X13 proof of work hash.
+const doubleHashBlockVersion = 7
+
+// BlockHash returns the hash which identifies the block with the passed
+// serialized header, as used for the previous block of a header, in inventory
+// vectors and by block locators.  For navcoin it matches the proof of work hash
+// returned by BlockPoWHash for headers with a version below 7, and is the double
+// SHA-256 of the header from version 7 on.  The version is decoded from the
+// first four bytes, and inputs too short to hold one are treated as version 0.
+//
+// Use BlockPoWHash for the hash which has to meet the target difficulty.
+func BlockHash(headerBytes []byte) Hash {
+	var version int32
+	if len(headerBytes) >= 4 {
+		version = int32(binary.LittleEndian.Uint32(headerBytes))
+	}
+	if version >= doubleHashBlockVersion {
+		return DoubleHashH(headerBytes)
+	}
+	return X13HashH(headerBytes)
+}
+
+// BlockPoWHash returns the X13 hash of the passed serialized header, which is
+// the navcoin proof of work hash every header is checked against its target
+// difficulty with, regardless of its version.  It does not identify blocks of
+// version 7 and later; use BlockHash for that.
+//
+// Like X13HashH, the zero hash is returned when the X13 hash can't be
+// calculated, so validation must use CheckProofOfWorkX13 instead.
+func BlockPoWHash(headerBytes []byte) Hash {
+	return X13HashH(headerBytes)
+}
+
+// RetargetAdjustmentFactor is the factor CalcNextRequiredDifficulty limits the
+// timespan of a retarget interval by.  The timespan is clamped to between the
+// target timespan divided by the factor and multiplied by it, so the target
+// difficulty can at most decrease to 25% or increase to 400% of its previous
+// value in a single retarget.  It matches the adjustment factor of every
+// navcoin network.
+const RetargetAdjustmentFactor = 4
+
+// CalcNextRequiredDifficulty returns the compact bits of the target difficulty
+// for the retarget interval following one with the passed actual timespan which
+// used the target difficulty represented by lastBits.  The new target is the
+// previous one scaled by the ratio of the actual timespan, after clamping it
+// according to RetargetAdjustmentFactor, to the target timespan:
+//
+//	newTarget = lastTarget * clampedTimespan / targetTimespan
+//
+// The timespans are truncated to whole seconds and, as in the reference
+// implementation, the result is rounded down by the integer division.  The new
+// target never exceeds the target represented by maxBits, which is the minimum
+// difficulty allowed by the network, and maxBits is returned when it would.
+// The target timespan must be at least one second, and lastBits is returned
+// unchanged otherwise.
+func CalcNextRequiredDifficulty(lastBits uint32, actualTimespan,
+	targetTimespan time.Duration, maxBits uint32) uint32 {
+
+	targetSecs := int64(targetTimespan / time.Second)
+	if targetSecs <= 0 {
+		return lastBits
+	}
+
+	// Limit the amount of adjustment that can occur to the previous
+	// difficulty.
+	actualSecs := int64(actualTimespan / time.Second)
+	minSecs := targetSecs / RetargetAdjustmentFactor
+	maxSecs := targetSecs * RetargetAdjustmentFactor
+	if actualSecs < minSecs {
+		actualSecs = minSecs
+	} else if actualSecs > maxSecs {
+		actualSecs = maxSecs
+	}
+
+	newTarget := CompactToBig(lastBits)
+	newTarget.Mul(newTarget, big.NewInt(actualSecs))
+	newTarget.Div(newTarget, big.NewInt(targetSecs))
+
+	// Limit the new target to the minimum difficulty.
+	if newTarget.Cmp(CompactToBig(maxBits)) > 0 {
+		return maxBits
+	}
+	return BigToCompact(newTarget)
+}
@@ -0,0 +1,276 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// navGenesisHeader is the serialized header of the genesis block of the main
+// navcoin network, which was mined with X13 against bits 0x1f00ffff.  Its hash
+// is navGenesisHash.
+const (
+	navGenesisHeader = "01000000" +
+		"0000000000000000000000000000000000000000000000000000000000000000" +
+		"1ac692738a35b20c57608a4b5946d9d242baafceaf64d73254fdabccc6ee07c5" +
+		"90640e57ffff001f211b0000"
+	navGenesisHash = "00006a4e3e18c71c6d48ad6c261e2254fa764cf29607a4357c99b712dfbb8e6a"
+)
+
+// TestCompactToBig ensures CompactToBig decodes compact bits, including those
+// with mantissas which are shifted out entirely and with the sign bit set, to
+// the expected numbers.  The vectors are taken from the reference
+// implementation.
+func TestCompactToBig(t *testing.T) {
+	tests := []struct {
+		in  uint32
+		out string
+	}{
+		{0x00000000, "0"},
+		{0x00123456, "0"},
+		{0x01003456, "0"},
+		{0x02000056, "0"},
+		{0x03000000, "0"},
+		{0x04000000, "0"},
+		{0x00923456, "0"},
+		{0x01803456, "0"},
+		{0x02800056, "0"},
+		{0x03800000, "0"},
+		{0x04800000, "0"},
+		{0x01123456, "12"},
+		{0x01fedcba, "-7e"},
+		{0x02123456, "1234"},
+		{0x03123456, "123456"},
+		{0x04123456, "12345600"},
+		{0x04923456, "-12345600"},
+		{0x05009234, "92340000"},
+		{0x20123456, "1234560000000000000000000000000000000000000000000000000000000000"},
+		{0x1d00ffff, "ffff0000000000000000000000000000000000000000000000000000"},
+	}
+
+	for i, test := range tests {
+		want, ok := new(big.Int).SetString(test.out, 16)
+		if !ok {
+			t.Fatalf("invalid number %q in test #%d", test.out, i)
+		}
+		if got := CompactToBig(test.in); got.Cmp(want) != 0 {
+			t.Errorf("CompactToBig #%d (%#08x): got %x, want %x", i,
+				test.in, got, want)
+		}
+	}
+}
+
+// TestBigToCompact ensures BigToCompact encodes numbers to the expected compact
+// bits, including numbers whose mantissa would have the sign bit set and
+// negative numbers.  The vectors are taken from the reference implementation.
+func TestBigToCompact(t *testing.T) {
+	tests := []struct {
+		in  string
+		out uint32
+	}{
+		{"0", 0x00000000},
+		{"12", 0x01120000},
+		{"-7e", 0x01fe0000},
+		{"1234", 0x02123400},
+		{"123456", 0x03123456},
+		{"12345600", 0x04123456},
+		{"-12345600", 0x04923456},
+		{"92340000", 0x05009234},
+		{"1234560000000000000000000000000000000000000000000000000000000000", 0x20123456},
+		{"ffff0000000000000000000000000000000000000000000000000000", 0x1d00ffff},
+
+		// Mantissas with the sign bit set are shifted into the next
+		// exponent.
+		{"80", 0x02008000},
+		{"800000", 0x04008000},
+		{"-800000", 0x04808000},
+		{"ffffff", 0x0400ffff},
+
+		// Only the most significant digits are kept.
+		{"1234567", 0x04012345},
+	}
+
+	for i, test := range tests {
+		n, ok := new(big.Int).SetString(test.in, 16)
+		if !ok {
+			t.Fatalf("invalid number %q in test #%d", test.in, i)
+		}
+		if got := BigToCompact(n); got != test.out {
+			t.Errorf("BigToCompact #%d (%s): got %#08x, want %#08x",
+				i, test.in, got, test.out)
+		}
+
+		// The passed number must not be modified.
+		if n.Text(16) != test.in {
+			t.Errorf("BigToCompact #%d modified the number to %x", i,
+				n)
+		}
+	}
+}
+
+// TestCalcNextRequiredDifficulty ensures the target difficulty is retargeted
+// in proportion to the timespan of the previous interval, within the limits of
+// the adjustment factor and the minimum difficulty.
+func TestCalcNextRequiredDifficulty(t *testing.T) {
+	const (
+		targetTimespan = 30 * time.Minute
+		maxBits        = 0x1d00ffff
+	)
+	tests := []struct {
+		name     string
+		lastBits uint32
+		actual   time.Duration
+		want     uint32
+	}{
+		{"on target", 0x1b0404cb, targetTimespan, 0x1b0404cb},
+		{"twice as slow", 0x1b0404cb, 2 * targetTimespan, 0x1b080996},
+		{"at min timespan", 0x1b0404cb, targetTimespan / 4, 0x1b010132},
+		{"too fast", 0x1b0404cb, targetTimespan / 10, 0x1b010132},
+		{"no time", 0x1b0404cb, 0, 0x1b010132},
+		{"negative time", 0x1b0404cb, -time.Hour, 0x1b010132},
+		{"at max timespan", 0x1b0404cb, targetTimespan * 4, 0x1b10132c},
+		{"too slow", 0x1b0404cb, targetTimespan * 10, 0x1b10132c},
+		{"sub-second", 0x1b0404cb, targetTimespan + time.Second - 1,
+			0x1b0404cb},
+		{"too slow at min difficulty", maxBits, targetTimespan * 2,
+			maxBits},
+		{"slow beyond min difficulty", 0x1c7fffff, targetTimespan * 4,
+			maxBits},
+		{"fast at min difficulty", maxBits, targetTimespan / 2,
+			0x1c7fff80},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		got := CalcNextRequiredDifficulty(test.lastBits, test.actual,
+			targetTimespan, maxBits)
+		if got != test.want {
+			t.Errorf("CalcNextRequiredDifficulty (%s): got %#08x, "+
+				"want %#08x", test.name, got, test.want)
+		}
+	}
+
+	// Target timespans below a second leave the difficulty unchanged.
+	got := CalcNextRequiredDifficulty(0x1b0404cb, time.Hour, 0, maxBits)
+	if got != 0x1b0404cb {
+		t.Errorf("CalcNextRequiredDifficulty (no target timespan): got "+
+			"%#08x, want %#08x", got, 0x1b0404cb)
+	}
+}
+
+// TestCheckProofOfWork ensures proof of work hashes are compared against the
+// targets represented by compact bits with the correct byte order.
+func TestCheckProofOfWork(t *testing.T) {
+	tests := []struct {
+		hash string
+		bits uint32
+		want bool
+	}{
+		// The main network genesis hash against its own target.
+		{navGenesisHash, 0x1f00ffff, true},
+
+		// The smallest targets with the same leading digits as the
+		// genesis hash.  0x00006a4e0000... is just below the hash and
+		// 0x00006a4f0000... just above it.
+		{navGenesisHash, 0x1f006a4e, false},
+		{navGenesisHash, 0x1f006a4f, true},
+
+		// A hash exactly equal to the target meets it.
+		{"00000000ffff0000000000000000000000000000000000000000000000000000", 0x1d00ffff, true},
+		{"00000000ffff0000000000000000000000000000000000000000000000000001", 0x1d00ffff, false},
+
+		// Interpreting the hash in the wrong byte order would reject
+		// the first hash and accept the second.
+		{"00000000000000000000000000000000000000000000000000000000000000ff", 0x1d00ffff, true},
+		{"ff00000000000000000000000000000000000000000000000000000000000000", 0x1d00ffff, false},
+
+		// Zero and negative targets are never met.
+		{"0000000000000000000000000000000000000000000000000000000000000000", 0, false},
+		{"0000000000000000000000000000000000000000000000000000000000000000", 0x1d80ffff, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		hash, err := NewHashFromStr(test.hash)
+		if err != nil {
+			t.Errorf("NewHashFromStr #%d: unexpected error: %v", i, err)
+			continue
+		}
+
+		result := CheckProofOfWork(hash, test.bits)
+		if result != test.want {
+			t.Errorf("CheckProofOfWork #%d (%v, %08x)\n got: %v "+
+				"want: %v", i, hash, test.bits, result, test.want)
+			continue
+		}
+	}
+}
+
+// TestCheckProofOfWorkX13 ensures the X13 proof of work of a known block header
+// is checked as expected.
+func TestCheckProofOfWorkX13(t *testing.T) {
+	header, err := hex.DecodeString(navGenesisHeader)
+	if err != nil {
+		t.Fatalf("unable to decode header: %v", err)
+	}
+
+	tests := []struct {
+		bits uint32
+		want bool
+	}{
+		{0x1f00ffff, true},
+		{0x1f006a4f, true},
+		{0x1f006a4e, false},
+		{0x1e00ffff, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := CheckProofOfWorkX13(header, test.bits)
+		if result != test.want {
+			t.Errorf("CheckProofOfWorkX13 #%d (%08x)\n got: %v "+
+				"want: %v", i, test.bits, result, test.want)
+			continue
+		}
+	}
+}
+
+// TestBlockHash ensures the block hash of a header is its X13 proof of work
+// hash before version 7 and its double SHA-256 hash from version 7 on, while
+// the proof of work hash is always the X13 hash.
+func TestBlockHash(t *testing.T) {
+	header, err := hex.DecodeString(navGenesisHeader)
+	if err != nil {
+		t.Fatalf("unable to decode header: %v", err)
+	}
+
+	// The genesis block is version 1, so it is identified by its X13 hash.
+	if got := BlockHash(header).String(); got != navGenesisHash {
+		t.Errorf("BlockHash (version 1)\n got: %v want: %v", got,
+			navGenesisHash)
+	}
+	if got := BlockPoWHash(header).String(); got != navGenesisHash {
+		t.Errorf("BlockPoWHash (version 1)\n got: %v want: %v", got,
+			navGenesisHash)
+	}
+
+	// The same header with version 7 is identified by its double SHA-256
+	// hash instead, while its proof of work hash is still the X13 hash.
+	header[0] = 0x07
+	want := "6c0cfce7387a651c6c21d580ad9bc0e3cdc9953945c64d57cf79d4d0fd031a79"
+	if got := BlockHash(header).String(); got != want {
+		t.Errorf("BlockHash (version 7)\n got: %v want: %v", got, want)
+	}
+	if got, want := BlockPoWHash(header), X13HashH(header); got != want {
+		t.Errorf("BlockPoWHash (version 7)\n got: %v want: %v", got,
+			want)
+	}
+	if BlockHash(header) == BlockPoWHash(header) {
+		t.Errorf("BlockHash (version 7): matches proof of work hash")
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import "fmt"
+
+// PowAlgo identifies a proof of work hashing algorithm.
+type PowAlgo uint8
+
+const (
+	// PowAlgoX13 is the X13 proof of work hashing algorithm.
+	PowAlgoX13 PowAlgo = iota
+
+	// PowAlgoSHA256D is the double SHA-256 proof of work hashing
+	// algorithm.
+	PowAlgoSHA256D
+)
+
+// PowHashFunc is a function which calculates the proof of work hash of b.
+type PowHashFunc func(b []byte) Hash
+
+// powHashFuncs maps the supported proof of work hashing algorithms to the
+// functions which calculate them.
+var powHashFuncs = map[PowAlgo]PowHashFunc{
+	PowAlgoX13:     X13HashH,
+	PowAlgoSHA256D: DoubleHashH,
+}
+
+// Map of proof of work hashing algorithms back to their constant names for
+// pretty printing.
+var powAlgoStrings = map[PowAlgo]string{
+	PowAlgoX13:     "PowAlgoX13",
+	PowAlgoSHA256D: "PowAlgoSHA256D",
+}
+
+// String returns the PowAlgo in human-readable form.
+func (a PowAlgo) String() string {
+	if s, ok := powAlgoStrings[a]; ok {
+		return s
+	}
+
+	return fmt.Sprintf("Unknown PowAlgo (%d)", uint8(a))
+}
+
+// PowHashFuncFor returns the function which calculates the proof of work hash
+// for the passed algorithm.  An error is returned for unknown algorithms.
+func PowHashFuncFor(algo PowAlgo) (PowHashFunc, error) {
+	f, ok := powHashFuncs[algo]
+	if !ok {
+		return nil, fmt.Errorf("unknown proof of work algorithm %v", algo)
+	}
+	return f, nil
+}
+
+// PowHash calculates the proof of work hash of b using the passed algorithm.
+// This allows callers, such as block validation, to select the algorithm
+// appropriate for a given height or network.  An error is returned for unknown
+// algorithms.
+func PowHash(algo PowAlgo, b []byte) (Hash, error) {
+	f, err := PowHashFuncFor(algo)
+	if err != nil {
+		return Hash{}, err
+	}
+	return f(b), nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import "testing"
+
+// TestPowHash ensures PowHash dispatches to the hash function of the requested
+// algorithm and rejects unknown algorithms.
+func TestPowHash(t *testing.T) {
+	data := []byte("navcoin proof of work")
+	tests := []struct {
+		algo PowAlgo
+		want Hash
+		str  string
+	}{
+		{PowAlgoX13, X13HashH(data), "PowAlgoX13"},
+		{PowAlgoSHA256D, DoubleHashH(data), "PowAlgoSHA256D"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		hash, err := PowHash(test.algo, data)
+		if err != nil {
+			t.Errorf("PowHash #%d (%v): unexpected error: %v", i,
+				test.algo, err)
+			continue
+		}
+		if hash != test.want {
+			t.Errorf("PowHash #%d (%v)\n got: %v want: %v", i,
+				test.algo, hash, test.want)
+			continue
+		}
+		if s := test.algo.String(); s != test.str {
+			t.Errorf("String #%d\n got: %s want: %s", i, s, test.str)
+			continue
+		}
+	}
+
+	// Ensure unknown algorithms are rejected.
+	unknown := PowAlgo(0xff)
+	if _, err := PowHash(unknown, data); err == nil {
+		t.Errorf("PowHash(%v): did not receive expected error", unknown)
+	}
+	if _, err := PowHashFuncFor(unknown); err == nil {
+		t.Errorf("PowHashFuncFor(%v): did not receive expected error",
+			unknown)
+	}
+	if s := unknown.String(); s != "Unknown PowAlgo (255)" {
+		t.Errorf("String\n got: %s want: Unknown PowAlgo (255)", s)
+	}
+}
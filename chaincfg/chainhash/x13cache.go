@@ -0,0 +1,79 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+// x13CacheSize is the maximum number of X13 hashes memoized by
+// X13HashHCached.  It bounds the memory used by the cache regardless of how
+// many distinct inputs are hashed, such as when an attacker floods the node
+// with headers.
+const x13CacheSize = 1024
+
+// x13CacheEntry is an entry in the X13 hash cache.
+type x13CacheEntry struct {
+	key  Hash // SHA256 of the input
+	hash Hash // X13 hash of the input
+}
+
+// x13Cache is a least recently used cache of X13 hashes keyed by the SHA256
+// of their inputs.
+var x13Cache = struct {
+	sync.Mutex
+	entries map[Hash]*list.Element
+	order   *list.List // front is most recently used
+}{
+	entries: make(map[Hash]*list.Element, x13CacheSize),
+	order:   list.New(),
+}
+
+// X13HashHCached calculates X13Hash(b) and returns the resulting bytes as a
+// Hash like X13HashH, except that the results for the most recently hashed
+// inputs are memoized.  This avoids recomputing the expensive X13 hash when the
+// same data, such as a block header, is hashed several times during validation.
+//
+// The cache is a correctness-neutral optimization: the returned hash is always
+// identical to the result of X13HashH for the same input.  Inputs are keyed by
+// their SHA256 hash, which is far cheaper to compute than X13.
+//
+// NOTE: This function is safe for concurrent access.
+func X13HashHCached(b []byte) Hash {
+	key := Hash(sha256.Sum256(b))
+
+	x13Cache.Lock()
+	if elem, ok := x13Cache.entries[key]; ok {
+		x13Cache.order.MoveToFront(elem)
+		hash := elem.Value.(*x13CacheEntry).hash
+		x13Cache.Unlock()
+		return hash
+	}
+	x13Cache.Unlock()
+
+	// Calculate the hash without holding the lock so concurrent callers
+	// are not serialized on the expensive computation.  The zero hash
+	// returned when the hash can't be calculated is not memoized.
+	hash, err := X13HashHChecked(b)
+	if err != nil {
+		return hash
+	}
+
+	x13Cache.Lock()
+	if _, ok := x13Cache.entries[key]; !ok {
+		if x13Cache.order.Len() >= x13CacheSize {
+			oldest := x13Cache.order.Back()
+			x13Cache.order.Remove(oldest)
+			delete(x13Cache.entries, oldest.Value.(*x13CacheEntry).key)
+		}
+		entry := &x13CacheEntry{key: key, hash: hash}
+		x13Cache.entries[key] = x13Cache.order.PushFront(entry)
+	}
+	x13Cache.Unlock()
+
+	return hash
+}
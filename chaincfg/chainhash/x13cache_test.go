@@ -0,0 +1,55 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainhash
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// TestX13HashHCached ensures the memoized X13 hash function returns the same
+// results as the uncached one and that the cache stays bounded.
+func TestX13HashHCached(t *testing.T) {
+	header := make([]byte, 80)
+	for i := 0; i < x13CacheSize*2; i++ {
+		binary.LittleEndian.PutUint32(header[76:], uint32(i))
+		want := X13HashH(header)
+
+		// Hash the same data twice so the second result comes from
+		// the cache.
+		for j := 0; j < 2; j++ {
+			if hash := X13HashHCached(header); hash != want {
+				t.Fatalf("X13HashHCached #%d.%d = %v, want %v",
+					i, j, hash, want)
+			}
+		}
+	}
+
+	x13Cache.Lock()
+	entries, order := len(x13Cache.entries), x13Cache.order.Len()
+	x13Cache.Unlock()
+	if entries != x13CacheSize || order != x13CacheSize {
+		t.Fatalf("cache holds %d entries (%d ordered), want %d",
+			entries, order, x13CacheSize)
+	}
+}
+
+// BenchmarkX13HashH benchmarks hashing the same block header repeatedly
+// without memoization.
+func BenchmarkX13HashH(b *testing.B) {
+	header := make([]byte, 80)
+	for i := 0; i < b.N; i++ {
+		X13HashH(header)
+	}
+}
+
+// BenchmarkX13HashHCached benchmarks hashing the same block header repeatedly
+// with memoization.
+func BenchmarkX13HashHCached(b *testing.B) {
+	header := make([]byte, 80)
+	for i := 0; i < b.N; i++ {
+		X13HashHCached(header)
+	}
+}
@@ -229,10 +229,8 @@ type Params struct {
 var MainNetParams = Params{
 	Name:        "mainnet",
 	Net:         wire.MainNet,
-	DefaultPort: "44440",
-	DNSSeeds: []DNSSeed{
-		{"seed.nav.community", false},
-	},
+	DefaultPort: wire.DefaultPort(wire.MainNet),
+	DNSSeeds:    dnsSeeds(wire.MainNet),
 
 	// Chain parameters
 	GenesisBlock:             &genesisBlock,
@@ -328,8 +326,8 @@ var MainNetParams = Params{
 var RegressionNetParams = Params{
 	Name:        "regtest",
 	Net:         wire.TestNet,
-	DefaultPort: "18444",
-	DNSSeeds:    []DNSSeed{},
+	DefaultPort: wire.DefaultPort(wire.TestNet),
+	DNSSeeds:    dnsSeeds(wire.TestNet),
 
 	// Chain parameters
 	GenesisBlock:             &regTestGenesisBlock,
@@ -402,11 +400,8 @@ var RegressionNetParams = Params{
 var TestNet3Params = Params{
 	Name:        "testnet3",
 	Net:         wire.TestNet3,
-	DefaultPort: "15556",
-	DNSSeeds: []DNSSeed{
-		{"176.9.19.245", false},
-		{"46.4.24.136", false},
-	},
+	DefaultPort: wire.DefaultPort(wire.TestNet3),
+	DNSSeeds:    dnsSeeds(wire.TestNet3),
 
 	// Chain parameters
 	GenesisBlock:             &testNet3GenesisBlock,
@@ -491,8 +486,8 @@ var TestNet3Params = Params{
 var SimNetParams = Params{
 	Name:        "simnet",
 	Net:         wire.SimNet,
-	DefaultPort: "18555",
-	DNSSeeds:    []DNSSeed{}, // NOTE: There must NOT be any seeds.
+	DefaultPort: wire.DefaultPort(wire.SimNet),
+	DNSSeeds:    dnsSeeds(wire.SimNet), // NOTE: There must NOT be any seeds.
 
 	// Chain parameters
 	GenesisBlock:             &simNetGenesisBlock,
@@ -586,6 +581,18 @@ func (d DNSSeed) String() string {
 	return d.Host
 }
 
+// dnsSeeds returns the DNS seeds of the passed network, whose hostnames are
+// defined by the wire package.  None of the seeds support filtering by service
+// flags.
+func dnsSeeds(net wire.NavCoinNet) []DNSSeed {
+	hosts := wire.DNSSeeds(net)
+	seeds := make([]DNSSeed, 0, len(hosts))
+	for _, host := range hosts {
+		seeds = append(seeds, DNSSeed{Host: host})
+	}
+	return seeds
+}
+
 // Register registers the network parameters for a NavCoin network.  This may
 // error with ErrDuplicateNet if the network is already registered (either
 // due to a previous Register call, or the network being one of the default
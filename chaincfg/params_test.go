@@ -4,7 +4,12 @@
 
 package chaincfg
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/encrypt-s/navd/wire"
+)
 
 // TestInvalidHashStr ensures the newShaHashFromStr function panics when used to
 // with an invalid hash string.
@@ -33,3 +38,27 @@ func TestMustRegisterPanic(t *testing.T) {
 	// Intentionally try to register duplicate params to force a panic.
 	mustRegister(&MainNetParams)
 }
+
+// TestNetworkDefaults ensures the default port and DNS seeds of each network
+// match those defined by the wire package.
+func TestNetworkDefaults(t *testing.T) {
+	for _, params := range []*Params{&MainNetParams, &RegressionNetParams,
+		&TestNet3Params, &SimNetParams} {
+
+		port := wire.DefaultPort(params.Net)
+		if params.DefaultPort != port {
+			t.Errorf("%s: default port %q does not match %q",
+				params.Name, params.DefaultPort, port)
+		}
+
+		var hosts []string
+		for _, seed := range params.DNSSeeds {
+			hosts = append(hosts, seed.Host)
+		}
+		seeds := wire.DNSSeeds(params.Net)
+		if !reflect.DeepEqual(hosts, seeds) {
+			t.Errorf("%s: DNS seeds %v do not match %v",
+				params.Name, hosts, seeds)
+		}
+	}
+}
@@ -80,7 +80,7 @@ type Config struct {
 	IsDeploymentActive func(deploymentID uint32) (bool, error)
 
 	// SigCache defines a signature cache to use.
-	SigCache *txscript.SigCache
+	SigCache txscript.SignatureCache
 
 	// HashCache defines the transaction hash mid-state cache to use.
 	HashCache *txscript.HashCache
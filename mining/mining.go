@@ -353,7 +353,7 @@ type BlkTmplGenerator struct {
 	txSource    TxSource
 	chain       *blockchain.BlockChain
 	timeSource  blockchain.MedianTimeSource
-	sigCache    *txscript.SigCache
+	sigCache    txscript.SignatureCache
 	hashCache   *txscript.HashCache
 }
 
@@ -366,7 +366,7 @@ type BlkTmplGenerator struct {
 func NewBlkTmplGenerator(policy *Policy, params *chaincfg.Params,
 	txSource TxSource, chain *blockchain.BlockChain,
 	timeSource blockchain.MedianTimeSource,
-	sigCache *txscript.SigCache,
+	sigCache txscript.SignatureCache,
 	hashCache *txscript.HashCache) *BlkTmplGenerator {
 
 	return &BlkTmplGenerator{
@@ -206,12 +206,12 @@ func messageSummary(msg wire.Message) string {
 		// logging.
 		rejCommand := sanitizeString(msg.Cmd, wire.CommandSize)
 		rejReason := sanitizeString(msg.Reason, maxRejectReasonLen)
-		summary := fmt.Sprintf("cmd %v, code %v, reason %v", rejCommand,
-			msg.Code, rejReason)
+		var hash *chainhash.Hash
 		if rejCommand == wire.CmdBlock || rejCommand == wire.CmdTx {
-			summary += fmt.Sprintf(", hash %v", msg.Hash)
+			hash = &msg.Hash
 		}
-		return summary
+		return fmt.Sprintf("cmd %v, %v", rejCommand,
+			wire.FormatReject(msg.Code, rejReason, hash))
 	}
 
 	// No summary for other messages.
@@ -31,7 +31,7 @@ const (
 
 	// minAcceptableProtocolVersion is the lowest protocol version that a
 	// connected peer may support.
-	minAcceptableProtocolVersion = wire.MultipleAddressVersion
+	minAcceptableProtocolVersion = wire.MinAcceptableProtocolVersion
 
 	// outputBufferSize is the number of elements the output channels use.
 	outputBufferSize = 50
@@ -439,6 +439,7 @@ type Peer struct {
 	sendHeadersPreferred bool   // peer sent a sendheaders message
 	verAckReceived       bool
 	witnessEnabled       bool
+	features             wire.Features // negotiated protocol features
 
 	wireEncoding wire.MessageEncoding
 
@@ -788,6 +789,18 @@ func (p *Peer) WantsHeaders() bool {
 	return sendHeadersPreferred
 }
 
+// Features returns the protocol features negotiated with the peer during the
+// version handshake.  All of them are unset before the handshake.
+//
+// This function is safe for concurrent access.
+func (p *Peer) Features() wire.Features {
+	p.flagsMtx.Lock()
+	features := p.features
+	p.flagsMtx.Unlock()
+
+	return features
+}
+
 // IsWitnessEnabled returns true if the peer has signalled that it supports
 // segregated witness.
 //
@@ -1052,10 +1065,10 @@ func (p *Peer) handleRemoteVersionMsg(msg *wire.MsgVersion) error {
 	// Notify and disconnect clients that have a protocol version that is
 	// too old.
 	//
-	// NOTE: If minAcceptableProtocolVersion is raised to be higher than
-	// wire.RejectVersion, this should send a reject packet before
+	// NOTE: If wire.MinAcceptableProtocolVersion is raised to be higher
+	// than wire.RejectVersion, this should send a reject packet before
 	// disconnecting.
-	if uint32(msg.ProtocolVersion) < minAcceptableProtocolVersion {
+	if !wire.IsProtocolCompatible(uint32(msg.ProtocolVersion)) {
 		reason := fmt.Sprintf("protocol version must be %d or greater",
 			minAcceptableProtocolVersion)
 		return errors.New(reason)
@@ -1087,11 +1100,12 @@ func (p *Peer) handleRemoteVersionMsg(msg *wire.MsgVersion) error {
 	// Set the remote peer's user agent.
 	p.userAgent = msg.UserAgent
 
-	// Determine if the peer would like to receive witness data with
-	// transactions, or not.
-	if p.services&wire.SFNodeWitness == wire.SFNodeWitness {
-		p.witnessEnabled = true
-	}
+	// Determine the protocol features available with the peer once so
+	// they don't need to be derived from the protocol version whenever a
+	// message is handled.  This includes whether the peer would like to
+	// receive witness data with transactions, or not.
+	p.features = wire.NegotiatedFeatures(p.protocolVersion, p.services)
+	p.witnessEnabled = p.features.Witness
 	p.flagsMtx.Unlock()
 
 	// Once the version message has been exchanged, we're able to determine
@@ -1112,7 +1126,7 @@ func (p *Peer) handleRemoteVersionMsg(msg *wire.MsgVersion) error {
 // is considered a successful ping.
 func (p *Peer) handlePingMsg(msg *wire.MsgPing) {
 	// Only reply with pong if the message is from a new enough client.
-	if p.ProtocolVersion() > wire.BIP0031Version {
+	if p.Features().PongNonce {
 		// Include nonce from ping so pong can be identified.
 		p.QueueMessage(wire.NewMsgPong(msg.Nonce), nil)
 	}
@@ -1130,7 +1144,7 @@ func (p *Peer) handlePongMsg(msg *wire.MsgPong) {
 	// and overlapping pings will be ignored. It is unlikely to occur
 	// without large usage of the ping rpc call since we ping infrequently
 	// enough that if they overlap we would have timed out the peer.
-	if p.ProtocolVersion() > wire.BIP0031Version {
+	if p.Features().PongNonce {
 		p.statsMtx.Lock()
 		if p.lastPingNonce != 0 && msg.Nonce == p.lastPingNonce {
 			p.lastPingMicros = time.Since(p.lastPingTime).Nanoseconds()
@@ -1858,7 +1872,7 @@ out:
 			case *wire.MsgPing:
 				// Only expects a pong message in later protocol
 				// versions.  Also set up statistics.
-				if p.ProtocolVersion() > wire.BIP0031Version {
+				if p.Features().PongNonce {
 					p.statsMtx.Lock()
 					p.lastPingNonce = m.Nonce
 					p.lastPingTime = time.Now()
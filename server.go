@@ -41,11 +41,6 @@ import (
 )
 
 const (
-	// defaultServices describes the default services that are supported by
-	// the server.
-	defaultServices = wire.SFNodeNetwork | wire.SFNodeBloom |
-		wire.SFNodeWitness | wire.SFNodeCF
-
 	// defaultRequiredServices describes the default services that are
 	// required to be supported by outbound peers.
 	defaultRequiredServices = wire.SFNodeNetwork
@@ -432,8 +427,7 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) {
 			// Request known addresses if the server address manager needs
 			// more and the peer has a protocol version new enough to
 			// include a timestamp with addresses.
-			hasTimestamp := sp.ProtocolVersion() >=
-				wire.NetAddressTimeVersion
+			hasTimestamp := wire.NetAddressHasTimestamp(sp.ProtocolVersion())
 			if addrManager.NeedMoreAddresses() && hasTimestamp {
 				sp.QueueMessage(wire.NewMsgGetAddr(), nil)
 			}
@@ -565,7 +559,7 @@ func (sp *serverPeer) OnInv(_ *peer.Peer, msg *wire.MsgInv) {
 		if invVect.Type == wire.InvTypeTx {
 			peerLog.Tracef("Ignoring tx %v in inv from %v -- "+
 				"blocksonly enabled", invVect.Hash, sp)
-			if sp.ProtocolVersion() >= wire.BIP0037Version {
+			if sp.Features().Bloom {
 				peerLog.Infof("Peer %v is announcing "+
 					"transactions -- disconnecting", sp)
 				sp.Disconnect()
@@ -1050,7 +1044,7 @@ func (sp *serverPeer) OnAddr(_ *peer.Peer, msg *wire.MsgAddr) {
 	}
 
 	// Ignore old style addresses which don't include a timestamp.
-	if sp.ProtocolVersion() < wire.NetAddressTimeVersion {
+	if !wire.NetAddressHasTimestamp(sp.ProtocolVersion()) {
 		return
 	}
 
@@ -2320,7 +2314,7 @@ func setupRPCListeners() ([]net.Listener, error) {
 // navcoin network type specified by chainParams.  Use start to begin accepting
 // connections from peers.
 func newServer(listenAddrs []string, db database.DB, chainParams *chaincfg.Params, interrupt <-chan struct{}) (*server, error) {
-	services := defaultServices
+	services := wire.DefaultServices(chainParams.Net)
 	if cfg.NoPeerBloomFilters {
 		services &^= wire.SFNodeBloom
 	}
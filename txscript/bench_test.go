@@ -0,0 +1,279 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/navcoin/navd/btcec"
+	"github.com/navcoin/navd/chaincfg/chainhash"
+)
+
+// benchSigTriplet houses a pre-generated signature triplet used by the
+// signature cache benchmarks so signing costs are not part of the timings.
+type benchSigTriplet struct {
+	sigHash chainhash.Hash
+	sig     *btcec.Signature
+	pubKey  *btcec.PublicKey
+}
+
+// genBenchSigTriplets returns the requested number of random signature
+// triplets, failing the benchmark on error.
+func genBenchSigTriplets(b *testing.B, count int) []benchSigTriplet {
+	triplets := make([]benchSigTriplet, count)
+	for i := range triplets {
+		msg, sig, key, err := genRandomSig()
+		if err != nil {
+			b.Fatalf("unable to generate random signature test data")
+		}
+		triplets[i] = benchSigTriplet{*msg, sig, key}
+	}
+	return triplets
+}
+
+// benchmarkSigCacheMempoolThenBlock simulates transactions being verified as
+// they enter the mempool followed by a block which includes the most recent
+// of them, and reports the hit rate of the block verification.  The cache is
+// smaller than the mempool so eviction takes place.
+func benchmarkSigCacheMempoolThenBlock(b *testing.B, newCache func(uint) *SigCache) {
+	const (
+		cacheSize   = 500
+		mempoolSize = 1000
+		blockSize   = 400
+	)
+	triplets := genBenchSigTriplets(b, mempoolSize)
+	blockTxns := triplets[mempoolSize-blockSize:]
+
+	var hits, lookups int
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sigCache := newCache(cacheSize)
+		for _, t := range triplets {
+			sigCache.Add(t.sigHash, t.sig, t.pubKey)
+		}
+		for _, t := range blockTxns {
+			if sigCache.Exists(t.sigHash, t.sig, t.pubKey) {
+				hits++
+			}
+			lookups++
+		}
+	}
+	b.StopTimer()
+	b.Logf("block hit rate: %.2f%%", float64(hits)*100/float64(lookups))
+}
+
+// BenchmarkSigCacheRandomMempoolThenBlock benchmarks the randomized eviction
+// policy under the mempool followed by block workload.
+func BenchmarkSigCacheRandomMempoolThenBlock(b *testing.B) {
+	benchmarkSigCacheMempoolThenBlock(b, NewSigCache)
+}
+
+// BenchmarkSigCacheLRUMempoolThenBlock benchmarks the least recently used
+// eviction policy under the mempool followed by block workload.
+func BenchmarkSigCacheLRUMempoolThenBlock(b *testing.B) {
+	benchmarkSigCacheMempoolThenBlock(b, NewSigCacheLRU)
+}
+
+// benchSigCache is a signature cache which can report its number of entries.
+type benchSigCache interface {
+	SignatureCache
+	Len() int
+}
+
+// benchmarkSigCacheParallelAdd benchmarks concurrent additions to and lookups
+// in the passed signature cache once it holds 'maxEntries' entries, so both
+// unsharded and sharded caches evict on every addition.  Run with -cpu to
+// compare the contention at different levels of parallelism.
+func benchmarkSigCacheParallelAdd(b *testing.B, sigCache benchSigCache, maxEntries int) {
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		b.Fatalf("unable to generate random signature test data")
+	}
+
+	// Fill the cache to capacity.  Random sigHashes are used so that every
+	// shard of a sharded cache fills up.
+	for sigCache.Len() < maxEntries {
+		var sigHash chainhash.Hash
+		if _, err := rand.Read(sigHash[:]); err != nil {
+			b.Fatalf("unable to generate random hash: %v", err)
+		}
+		sigCache.Add(sigHash, sig, key)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var sigHash chainhash.Hash
+		if _, err := rand.Read(sigHash[:]); err != nil {
+			b.Fatalf("unable to generate random hash: %v", err)
+		}
+		for i := uint32(0); pb.Next(); i++ {
+			binary.LittleEndian.PutUint32(sigHash[4:], i)
+			sigCache.Add(sigHash, sig, key)
+			sigCache.Exists(sigHash, sig, key)
+		}
+	})
+}
+
+// BenchmarkSigCacheParallelAdd benchmarks concurrent use of a single
+// signature cache.
+func BenchmarkSigCacheParallelAdd(b *testing.B) {
+	benchmarkSigCacheParallelAdd(b, NewSigCache(10000), 10000)
+}
+
+// BenchmarkShardedSigCacheParallelAdd benchmarks concurrent use of a sharded
+// signature cache.
+func BenchmarkShardedSigCacheParallelAdd(b *testing.B) {
+	benchmarkSigCacheParallelAdd(b, NewShardedSigCache(10000, 16), 10000)
+}
+
+// BenchmarkSigCacheAddFull benchmarks adding new entries to a full signature
+// cache, which evicts an entry on every addition, at several cache sizes.  The
+// time per addition should not depend on the size of the cache.
+func BenchmarkSigCacheAddFull(b *testing.B) {
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		b.Fatalf("unable to generate random signature test data")
+	}
+
+	for _, maxEntries := range []uint{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("%d", maxEntries), func(b *testing.B) {
+			sigCache := NewSigCache(maxEntries)
+			var sigHash chainhash.Hash
+			for i := uint32(0); uint(i) < maxEntries; i++ {
+				binary.LittleEndian.PutUint32(sigHash[:], i)
+				sigCache.Add(sigHash, sig, key)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				binary.LittleEndian.PutUint32(sigHash[:],
+					uint32(maxEntries)+uint32(i))
+				sigCache.Add(sigHash, sig, key)
+			}
+		})
+	}
+}
+
+// BenchmarkSigCacheBatchBurst benchmarks bursts of additions to a full
+// signature cache with several eviction batch sizes and reports the number of
+// eviction passes, which decreases as the batch size grows.
+func BenchmarkSigCacheBatchBurst(b *testing.B) {
+	const (
+		maxEntries = 10000
+		burstSize  = 1000
+	)
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		b.Fatalf("unable to generate random signature test data")
+	}
+
+	for _, evictBatch := range []uint{1, 16, 256} {
+		b.Run(fmt.Sprintf("batch%d", evictBatch), func(b *testing.B) {
+			sigCache := NewSigCacheBatch(maxEntries, evictBatch)
+			var sigHash chainhash.Hash
+			var next uint32
+			for ; next < maxEntries; next++ {
+				binary.LittleEndian.PutUint32(sigHash[:], next)
+				sigCache.Add(sigHash, sig, key)
+			}
+
+			var passes int
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < burstSize; j++ {
+					binary.LittleEndian.PutUint32(sigHash[:], next)
+					next++
+					prevLen := sigCache.numEntries
+					sigCache.Add(sigHash, sig, key)
+					if sigCache.numEntries <= prevLen {
+						passes++
+					}
+				}
+			}
+			b.StopTimer()
+			b.Logf("eviction passes per burst: %.2f",
+				float64(passes)/float64(b.N))
+		})
+	}
+}
+
+// BenchmarkSigCacheExistsSameSigHash benchmarks looking up a signature under a
+// sigHash with entries for many other keys, such as those of a multisig
+// script.  The full comparison variant clears the key tags of the entries to
+// show the cost of comparing every key in full.
+func BenchmarkSigCacheExistsSameSigHash(b *testing.B) {
+	const numKeys = 16
+	triplets := genBenchSigTriplets(b, numKeys+1)
+	sigHash := triplets[0].sigHash
+
+	for _, clearTags := range []bool{false, true} {
+		name := "tagged"
+		if clearTags {
+			name = "full compare"
+		}
+		b.Run(name, func(b *testing.B) {
+			sigCache := NewSigCache(numKeys)
+			for _, t := range triplets[:numKeys] {
+				sigCache.Add(sigHash, t.sig, t.pubKey)
+			}
+			if clearTags {
+				for _, entry := range sigCache.validSigs[sigHash] {
+					entry.keyTag = 0
+				}
+			}
+
+			// Look up a signature under the sigHash by a key which
+			// has no entry.
+			miss := triplets[numKeys]
+			want := newECDSAEntry(miss.sig, miss.pubKey, 0)
+			if clearTags {
+				want.keyTag = 0
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				sigCache.RLock()
+				sigCache.lookup(sigHash, want)
+				sigCache.RUnlock()
+			}
+		})
+	}
+}
+
+// BenchmarkSigCacheFill benchmarks filling an empty signature cache to its
+// maximum number of entries, as happens during the initial mempool fill, with a
+// map preallocated from the size hint and with a map which starts out empty and
+// therefore grows repeatedly.
+func BenchmarkSigCacheFill(b *testing.B) {
+	const maxEntries = 50000
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		b.Fatalf("unable to generate random signature test data")
+	}
+
+	for _, preallocate := range []bool{true, false} {
+		name := "hint"
+		if !preallocate {
+			name = "no hint"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			var sigHash chainhash.Hash
+			for i := 0; i < b.N; i++ {
+				sigCache := NewSigCache(maxEntries)
+				if !preallocate {
+					sigCache.validSigs = make(
+						map[chainhash.Hash][]*sigCacheEntry)
+				}
+				for j := uint32(0); j < maxEntries; j++ {
+					binary.LittleEndian.PutUint32(sigHash[:], j)
+					sigCache.Add(sigHash, sig, key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+// CacheStats houses usage statistics for one of the caches used to verify
+// scripts along with its maximum number of entries.  It holds the same
+// counters as SigCacheStats so the statistics of the different caches can be
+// reported together.
+type CacheStats struct {
+	// Entries is the number of entries in the cache.
+	Entries uint64
+
+	// MaxEntries is the maximum number of entries allowed in the cache.
+	MaxEntries uint64
+
+	// Hits is the number of lookups which found a matching entry.
+	Hits uint64
+
+	// Misses is the number of lookups which did not find a matching
+	// entry.
+	Misses uint64
+
+	// Evictions is the number of entries removed to make room for new
+	// entries.
+	Evictions uint64
+}
+
+// add adds the statistics of another cache to the statistics.
+func (s *CacheStats) add(other *CacheStats) {
+	s.Entries += other.Entries
+	s.MaxEntries += other.MaxEntries
+	s.Hits += other.Hits
+	s.Misses += other.Misses
+	s.Evictions += other.Evictions
+}
+
+// VerifyCacheInfo houses the statistics of every cache used to verify the
+// scripts of transactions, as returned by CacheInfo, in a form suitable for
+// reporting, such as by the getcacheinfo RPC.
+type VerifyCacheInfo struct {
+	// SigCache, ScriptCache and ScriptResultCache hold the statistics of
+	// the individual caches.  They are zero for caches which are not used.
+	SigCache          CacheStats
+	ScriptCache       CacheStats
+	ScriptResultCache CacheStats
+
+	// Total holds the statistics of all caches summed together.
+	Total CacheStats
+}
+
+// CacheInfo returns the statistics of the passed caches, which are the caches
+// used by VerifyBlockScripts, along with their totals.  Any of the caches may
+// be nil, in which case its statistics are zero.  Signature caches other than
+// SigCache and ShardedSigCache don't keep statistics and are reported as zero
+// as well.
+//
+// It does not allocate and only briefly takes the read lock of each cache, so
+// it may be polled frequently while the caches are in use.
+//
+// NOTE: This function is safe for concurrent access.
+func CacheInfo(sigCache SignatureCache, scriptCache *ScriptCache,
+	resultCache *ScriptResultCache) VerifyCacheInfo {
+
+	var info VerifyCacheInfo
+	switch c := sigCache.(type) {
+	case *SigCache:
+		if c != nil {
+			info.SigCache = c.cacheStats()
+		}
+	case *ShardedSigCache:
+		if c != nil {
+			for _, shard := range c.shards {
+				shardStats := shard.cacheStats()
+				info.SigCache.add(&shardStats)
+			}
+		}
+	}
+	if scriptCache != nil {
+		info.ScriptCache = scriptCache.Stats()
+	}
+	if resultCache != nil {
+		info.ScriptResultCache = resultCache.Stats()
+	}
+
+	info.Total.add(&info.SigCache)
+	info.Total.add(&info.ScriptCache)
+	info.Total.add(&info.ScriptResultCache)
+	return info
+}
+
+// cacheStats returns the usage statistics of the signature cache along with its
+// maximum number of entries.
+func (s *SigCache) cacheStats() CacheStats {
+	s.RLock()
+	maxEntries := s.maxEntries
+	s.RUnlock()
+
+	stats := s.Stats()
+	return CacheStats{
+		Entries:    stats.Entries,
+		MaxEntries: uint64(maxEntries),
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+		Evictions:  stats.Evictions,
+	}
+}
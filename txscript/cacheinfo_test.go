@@ -0,0 +1,93 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"testing"
+
+	"github.com/navcoin/navd/chaincfg/chainhash"
+)
+
+// TestCacheInfo ensures CacheInfo reports the statistics of each of the passed
+// caches and sums them correctly, without allocating.
+func TestCacheInfo(t *testing.T) {
+	sigCache := NewSigCacheWithSource(1, &fixedSource{values: []uint64{0}})
+	for i := 0; i < 2; i++ {
+		msg, sig, key, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		sigCache.Add(*msg, sig, key)
+		sigCache.Exists(*msg, sig, key)
+		sigCache.Exists(chainhash.Hash{}, sig, key)
+	}
+
+	scriptCache := NewScriptCache(10)
+	cachedErr := scriptError(ErrScriptTooBig, "too big")
+	scriptCache.Add(nil, []byte{OP_1}, 0, cachedErr)
+	scriptCache.Lookup(nil, []byte{OP_1}, 0)
+	scriptCache.Lookup(nil, []byte{OP_2}, 0)
+	scriptCache.Lookup(nil, []byte{OP_3}, 0)
+
+	resultCache := NewScriptResultCacheWithSource(2,
+		&fixedSource{values: []uint64{1}})
+	for i := 0; i < 3; i++ {
+		resultCache.Add(chainhash.Hash{byte(i)}, true)
+		resultCache.Lookup(chainhash.Hash{byte(i)})
+	}
+
+	info := CacheInfo(sigCache, scriptCache, resultCache)
+	tests := []struct {
+		name string
+		got  CacheStats
+		want CacheStats
+	}{
+		{"SigCache", info.SigCache, CacheStats{Entries: 1, MaxEntries: 1,
+			Hits: 2, Misses: 2, Evictions: 1}},
+		{"ScriptCache", info.ScriptCache, CacheStats{Entries: 1,
+			MaxEntries: 10, Hits: 1, Misses: 2}},
+		{"ScriptResultCache", info.ScriptResultCache, CacheStats{
+			Entries: 2, MaxEntries: 2, Hits: 3, Evictions: 1}},
+		{"Total", info.Total, CacheStats{Entries: 4, MaxEntries: 13,
+			Hits: 6, Misses: 4, Evictions: 2}},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("CacheInfo (%s): got %+v, want %+v", test.name,
+				test.got, test.want)
+		}
+	}
+
+	// The statistics of a sharded signature cache are summed across its
+	// shards.
+	sharded := NewShardedSigCache(10, 3)
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	sharded.Add(*msg, sig, key)
+	sharded.Exists(*msg, sig, key)
+	info = CacheInfo(sharded, nil, nil)
+	want := CacheStats{Entries: 1, MaxEntries: 10, Hits: 1}
+	if info.SigCache != want || info.Total != want {
+		t.Errorf("CacheInfo (sharded): got %+v, want %+v",
+			info.SigCache, want)
+	}
+
+	// Caches which are not used or don't keep statistics are zero.
+	var nilSigCache *SigCache
+	for _, sigCache := range []SignatureCache{nil, nilSigCache, NopSigCache{}} {
+		if info := CacheInfo(sigCache, nil, nil); info != (VerifyCacheInfo{}) {
+			t.Errorf("CacheInfo: got %+v for unused caches", info)
+		}
+	}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		CacheInfo(sigCache, scriptCache, resultCache)
+	})
+	if allocs != 0 {
+		t.Fatalf("CacheInfo: got %v allocations, want 0", allocs)
+	}
+}
@@ -128,7 +128,7 @@ type Engine struct {
 	condStack       []int
 	numOps          int
 	flags           ScriptFlags
-	sigCache        *SigCache
+	sigCache        SignatureCache
 	hashCache       *TxSigHashes
 	bip16           bool     // treat execution as pay-to-script-hash
 	savedFirstStack [][]byte // stack from first script for bip16 scripts
@@ -571,8 +571,7 @@ func (vm *Engine) checkHashTypeEncoding(hashType SigHashType) error {
 		return nil
 	}
 
-	sigHashType := hashType & ^SigHashAnyOneCanPay
-	if sigHashType < SigHashAll || sigHashType > SigHashSingle {
+	if !isValidSigHashType(hashType) {
 		str := fmt.Sprintf("invalid hash type 0x%x", hashType)
 		return scriptError(ErrInvalidSigHashType, str)
 	}
@@ -801,7 +800,7 @@ func (vm *Engine) SetAltStack(data [][]byte) {
 // transaction, and input index.  The flags modify the behavior of the script
 // engine according to the description provided by each flag.
 func NewEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags,
-	sigCache *SigCache, hashCache *TxSigHashes, inputAmount int64) (*Engine, error) {
+	sigCache SignatureCache, hashCache *TxSigHashes, inputAmount int64) (*Engine, error) {
 
 	// The provided transaction input index must refer to a valid input.
 	if txIdx < 0 || txIdx >= len(tx.TxIn) {
@@ -820,6 +819,10 @@ func NewEngine(scriptPubKey []byte, tx *wire.MsgTx, txIdx int, flags ScriptFlags
 			"false stack entry at end of script execution")
 	}
 
+	// A nil signature cache disables caching.  Substitute the no-op cache so
+	// the signature checking opcodes never need to branch on it.
+	sigCache = normalizeSigCache(sigCache)
+
 	// The clean stack flag (ScriptVerifyCleanStack) is not allowed without
 	// either the the pay-to-script-hash (P2SH) evaluation (ScriptBip16)
 	// flag or the Segregated Witness (ScriptVerifyWitness) flag.
@@ -0,0 +1,65 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"math/rand"
+
+	"github.com/navcoin/navd/chaincfg/chainhash"
+)
+
+// evictionEntry is embedded in the entries of the caches which use an
+// evictionList to choose the entries they evict.
+type evictionEntry struct {
+	key chainhash.Hash
+
+	// index is the position of the entry in the slice of entries the
+	// random victims are chosen from.
+	index int
+}
+
+// evictionList holds every entry of a cache in a dense slice in no particular
+// order, so that a random entry can be chosen for eviction and any entry can be
+// removed in constant time.  It is shared by ScriptCache and ScriptResultCache,
+// which map their keys to their entries themselves.
+//
+// The list is not safe for concurrent access, so it must only be used with the
+// write lock of the cache it belongs to held.
+type evictionList struct {
+	// randSource provides the randomness used to choose which entry is
+	// evicted.
+	randSource rand.Source64
+	entries    []*evictionEntry
+}
+
+// add appends the passed entry to the list.
+func (l *evictionList) add(entry *evictionEntry) {
+	entry.index = len(l.entries)
+	l.entries = append(l.entries, entry)
+}
+
+// remove removes the passed entry, which must be in the list, from the list.
+func (l *evictionList) remove(entry *evictionEntry) {
+	// Move the last entry into the slot of the removed one so the slice of
+	// entries stays dense.
+	last := len(l.entries) - 1
+	l.entries[entry.index] = l.entries[last]
+	l.entries[entry.index].index = entry.index
+	l.entries[last] = nil
+	l.entries = l.entries[:last]
+}
+
+// evict removes a random entry from the list when adding another one would make
+// the number of entries exceed the passed max, and returns the key of the
+// removed entry so the caller can remove it from its cache as well, along with
+// whether an entry was removed.  Nothing is removed from an empty list.
+func (l *evictionList) evict(maxEntries uint) (chainhash.Hash, bool) {
+	if len(l.entries) == 0 || uint(len(l.entries))+1 <= maxEntries {
+		return chainhash.Hash{}, false
+	}
+	victim := l.entries[randomIndex(l.randSource, len(l.entries))]
+	l.remove(victim)
+	return victim.key, true
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"testing"
+
+	"github.com/navcoin/navd/chaincfg/chainhash"
+)
+
+// TestEvictionList ensures an eviction list only evicts once adding another
+// entry would exceed the max, evicts the entry chosen by its source of
+// randomness and keeps its entries dense.
+func TestEvictionList(t *testing.T) {
+	list := evictionList{randSource: &fixedSource{values: []uint64{1}}}
+	if _, ok := list.evict(0); ok {
+		t.Fatalf("evict: entry evicted from empty list")
+	}
+
+	for i := 0; i < 3; i++ {
+		list.add(&evictionEntry{key: chainhash.Hash{byte(i)}})
+	}
+	if _, ok := list.evict(4); ok {
+		t.Fatalf("evict: entry evicted from list with room")
+	}
+	victim, ok := list.evict(3)
+	if !ok || victim != (chainhash.Hash{1}) {
+		t.Fatalf("evict: got %v (evicted %v), want %v", victim, ok,
+			chainhash.Hash{1})
+	}
+
+	if len(list.entries) != 2 {
+		t.Fatalf("evict: list holds %d entries, want 2",
+			len(list.entries))
+	}
+	for j, entry := range list.entries {
+		if entry.index != j {
+			t.Fatalf("evict: entry at %d has index %d", j, entry.index)
+		}
+		if entry.key == victim {
+			t.Fatalf("evict: evicted entry %v still in list", victim)
+		}
+	}
+}
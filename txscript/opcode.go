@@ -2000,8 +2000,7 @@ func opcodeHash160(op *parsedOpcode, vm *Engine) error {
 		return err
 	}
 
-	hash := sha256.Sum256(buf)
-	vm.dstack.PushByteArray(calcHash(hash[:], ripemd160.New()))
+	vm.dstack.PushByteArray(chainhash.Hash160B(buf))
 	return nil
 }
 
@@ -2130,18 +2129,13 @@ func opcodeCheckSig(op *parsedOpcode, vm *Engine) error {
 		return nil
 	}
 
-	var valid bool
-	if vm.sigCache != nil {
-		var sigHash chainhash.Hash
-		copy(sigHash[:], hash)
+	var sigHash chainhash.Hash
+	copy(sigHash[:], hash)
 
-		valid = vm.sigCache.Exists(sigHash, signature, pubKey)
-		if !valid && signature.Verify(hash, pubKey) {
-			vm.sigCache.Add(sigHash, signature, pubKey)
-			valid = true
-		}
-	} else {
-		valid = signature.Verify(hash, pubKey)
+	valid := vm.sigCache.Exists(sigHash, signature, pubKey)
+	if !valid && signature.Verify(hash, pubKey) {
+		vm.sigCache.Add(sigHash, signature, pubKey)
+		valid = true
 	}
 
 	if !valid && vm.hasFlag(ScriptVerifyNullFail) && len(sigBytes) > 0 {
@@ -2380,18 +2374,13 @@ func opcodeCheckMultiSig(op *parsedOpcode, vm *Engine) error {
 			hash = calcSignatureHash(script, hashType, &vm.tx, vm.txIdx)
 		}
 
-		var valid bool
-		if vm.sigCache != nil {
-			var sigHash chainhash.Hash
-			copy(sigHash[:], hash)
+		var sigHash chainhash.Hash
+		copy(sigHash[:], hash)
 
-			valid = vm.sigCache.Exists(sigHash, parsedSig, parsedPubKey)
-			if !valid && parsedSig.Verify(hash, parsedPubKey) {
-				vm.sigCache.Add(sigHash, parsedSig, parsedPubKey)
-				valid = true
-			}
-		} else {
-			valid = parsedSig.Verify(hash, parsedPubKey)
+		valid := vm.sigCache.Exists(sigHash, parsedSig, parsedPubKey)
+		if !valid && parsedSig.Verify(hash, parsedPubKey) {
+			vm.sigCache.Add(sigHash, parsedSig, parsedPubKey)
+			valid = true
 		}
 
 		if valid {
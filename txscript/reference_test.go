@@ -319,15 +319,9 @@ type scriptWithInputVal struct {
 }
 
 // testScripts ensures all of the passed script tests execute with the expected
-// results with or without using a signature cache, as specified by the
-// parameter.
-func testScripts(t *testing.T, tests [][]interface{}, useSigCache bool) {
-	// Create a signature cache to use only if requested.
-	var sigCache *SigCache
-	if useSigCache {
-		sigCache = NewSigCache(10)
-	}
-
+// results using the provided signature cache, which may be nil to run without
+// one.
+func testScripts(t *testing.T, tests [][]interface{}, sigCache SignatureCache) {
 	for i, test := range tests {
 		// "Format is: [[wit..., amount]?, scriptSig, scriptPubKey,
 		//    flags, expected_scripterror, ... comments]"
@@ -487,9 +481,11 @@ func TestScripts(t *testing.T) {
 		t.Fatalf("TestScripts couldn't Unmarshal: %v", err)
 	}
 
-	// Run all script tests with and without the signature cache.
-	testScripts(t, tests, true)
-	testScripts(t, tests, false)
+	// Run all script tests with and without the signature cache.  The
+	// no-op cache must produce exactly the same results as no cache.
+	testScripts(t, tests, NewSigCache(10))
+	testScripts(t, tests, nil)
+	testScripts(t, tests, NopSigCache{})
 }
 
 // testVecF64ToUint32 properly handles conversion of float64s read from the JSON
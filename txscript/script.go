@@ -554,6 +554,50 @@ func CalcWitnessSigHash(script []byte, sigHashes *TxSigHashes, hType SigHashType
 		amt)
 }
 
+// isValidSigHashType returns whether the passed hash type is one of
+// SigHashAll, SigHashNone or SigHashSingle, optionally combined with
+// SigHashAnyOneCanPay.
+func isValidSigHashType(hashType SigHashType) bool {
+	sigHashType := hashType & ^SigHashAnyOneCanPay
+	return sigHashType >= SigHashAll && sigHashType <= SigHashSingle
+}
+
+// CalcSignatureHash computes the signature hash digest for the specified input
+// of the target transaction observing the desired signature hash type.  This is
+// the digest which is signed and verified, and on which the signature cache is
+// keyed, for inputs which do not spend witness programs.  See
+// CalcWitnessSigHash for those.
+//
+// An error is returned if the hash type is not one of SigHashAll, SigHashNone
+// or SigHashSingle, optionally combined with SigHashAnyOneCanPay, if the input
+// index is out of range, or if the script fails to parse.
+//
+// NOTE: As required by consensus, the returned hash is 1 (as a uint256 little
+// endian), rather than an error, when SigHashSingle is used for an input which
+// has no corresponding output.  Signing that hash is dangerous as the signature
+// can be reused to spend any other such input with the same key.
+func CalcSignatureHash(script []byte, hashType SigHashType, tx *wire.MsgTx,
+	idx int) (chainhash.Hash, error) {
+
+	if !isValidSigHashType(hashType) {
+		str := fmt.Sprintf("invalid hash type 0x%x", hashType)
+		return chainhash.Hash{}, scriptError(ErrInvalidSigHashType, str)
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		str := fmt.Sprintf("transaction input index %d is negative or "+
+			">= %d", idx, len(tx.TxIn))
+		return chainhash.Hash{}, scriptError(ErrInvalidIndex, str)
+	}
+	parsedScript, err := parseScript(script)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	var hash chainhash.Hash
+	copy(hash[:], calcSignatureHash(parsedScript, hashType, tx, idx))
+	return hash, nil
+}
+
 // shallowCopyTx creates a shallow copy of the transaction for use when
 // calculating the signature hash.  It is used over the Copy method on the
 // transaction itself since that is a deep copy and therefore does more work and
@@ -9,6 +9,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/navcoin/navd/chaincfg/chainhash"
 	"github.com/navcoin/navd/wire"
 )
 
@@ -4312,3 +4313,93 @@ func TestIsUnspendable(t *testing.T) {
 		}
 	}
 }
+
+// TestCalcSignatureHashTypes ensures CalcSignatureHash validates the hash type
+// and input index and returns the hash of 1 required by consensus when
+// SigHashSingle is used for an input without a corresponding output.
+func TestCalcSignatureHashTypes(t *testing.T) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for i := 0; i < 2; i++ {
+		prevOut := wire.NewOutPoint(&chainhash.Hash{0x01}, uint32(i))
+		tx.AddTxIn(wire.NewTxIn(prevOut, nil, nil))
+	}
+	tx.AddTxOut(wire.NewTxOut(1, []byte{OP_TRUE}))
+	script := []byte{OP_TRUE}
+
+	var one chainhash.Hash
+	one[0] = 0x01
+
+	tests := []struct {
+		name     string
+		script   []byte
+		hashType SigHashType
+		idx      int
+		err      ErrorCode
+		valid    bool
+	}{
+		{"all", script, SigHashAll, 0, 0, true},
+		{"none", script, SigHashNone, 1, 0, true},
+		{"single", script, SigHashSingle, 0, 0, true},
+		{"all anyonecanpay", script, SigHashAll | SigHashAnyOneCanPay,
+			1, 0, true},
+		{"single anyonecanpay", script,
+			SigHashSingle | SigHashAnyOneCanPay, 0, 0, true},
+		{"single out of range", script, SigHashSingle, 1, 0, true},
+		{"single anyonecanpay out of range", script,
+			SigHashSingle | SigHashAnyOneCanPay, 1, 0, true},
+		{"old", script, SigHashOld, 0, ErrInvalidSigHashType, false},
+		{"undefined", script, 0x04, 0, ErrInvalidSigHashType, false},
+		{"undefined anyonecanpay", script, 0x84, 0,
+			ErrInvalidSigHashType, false},
+		{"anyonecanpay only", script, SigHashAnyOneCanPay, 0,
+			ErrInvalidSigHashType, false},
+		{"negative index", script, SigHashAll, -1, ErrInvalidIndex,
+			false},
+		{"index out of range", script, SigHashAll, 2, ErrInvalidIndex,
+			false},
+		{"malformed script", []byte{OP_DATA_2, 0x01}, SigHashAll, 0,
+			ErrMalformedPush, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		hash, err := CalcSignatureHash(test.script, test.hashType, tx,
+			test.idx)
+		if (err == nil) != test.valid {
+			t.Errorf("CalcSignatureHash (%s): unexpected error "+
+				"state: %v", test.name, err)
+			continue
+		}
+		if !test.valid {
+			if !IsErrorCode(err, test.err) {
+				t.Errorf("CalcSignatureHash (%s): got error %v, "+
+					"want %v", test.name, err, test.err)
+			}
+			continue
+		}
+
+		// SigHashSingle without a corresponding output must produce
+		// the hash of 1 while all other hashes must match the digest
+		// computed by the engine.
+		want := one
+		if test.hashType&sigHashMask != SigHashSingle ||
+			test.idx < len(tx.TxOut) {
+
+			parsedScript, err := parseScript(test.script)
+			if err != nil {
+				t.Fatalf("unable to parse script: %v", err)
+			}
+			copy(want[:], calcSignatureHash(parsedScript,
+				test.hashType, tx, test.idx))
+			if want == one {
+				t.Errorf("CalcSignatureHash (%s): unexpected "+
+					"hash of 1", test.name)
+				continue
+			}
+		}
+		if hash != want {
+			t.Errorf("CalcSignatureHash (%s): got %v, want %v",
+				test.name, hash, want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/navcoin/navd/chaincfg/chainhash"
+)
+
+// scriptCacheEntry represents a signature script and public key script pair
+// which is known to be invalid under a set of script flags.
+type scriptCacheEntry struct {
+	evictionEntry
+	err Error
+}
+
+// ScriptCache implements a cache of signature script and public key script
+// pairs which are known to be invalid under a given set of script flags, with
+// a randomized entry eviction policy like SigCache.  It allows an input which
+// an attacker replays across many transactions to be rejected without creating
+// a new script engine for it each time.
+//
+// Only failures which are fully determined by the scripts and the flags are
+// cached, such as malformed or oversized scripts, signature scripts which are
+// not push only when required and invalid flag combinations.  Failures during
+// execution, or which depend on the witness, may differ between transactions
+// spending the same scripts and are never cached, and neither are successes.
+type ScriptCache struct {
+	// The following variables must only be used atomically.  They are
+	// placed first to ensure 64-bit alignment on 32-bit platforms.
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	sync.RWMutex
+	invalid    map[chainhash.Hash]*scriptCacheEntry
+	maxEntries uint
+
+	// evictList holds every entry in the cache so a random one can be
+	// evicted.  It is only accessed with the write lock held.
+	evictList evictionList
+}
+
+// NewScriptCache creates and initializes a new instance of ScriptCache.  Its
+// sole parameter 'maxEntries' represents the maximum number of entries allowed
+// to exist in the ScriptCache at any particular moment.  Random entries are
+// evicted to make room for new entries that would cause the number of entries
+// in the cache to exceed the max.
+func NewScriptCache(maxEntries uint) *ScriptCache {
+	return NewScriptCacheWithSource(maxEntries, newEvictionSource())
+}
+
+// NewScriptCacheWithSource creates and initializes a new instance of
+// ScriptCache which chooses the entry to evict using the provided source of
+// randomness.  Like NewSigCacheWithSource, it is primarily useful for tests and
+// callers must ensure the source is securely seeded when the cache is used for
+// validation.
+func NewScriptCacheWithSource(maxEntries uint, source rand.Source64) *ScriptCache {
+	sizeHint := cacheSizeHint(maxEntries)
+	return &ScriptCache{
+		invalid:    make(map[chainhash.Hash]*scriptCacheEntry, sizeHint),
+		maxEntries: maxEntries,
+		evictList:  evictionList{randSource: source},
+	}
+}
+
+// scriptCacheKey returns the key under which the failure of the passed script
+// pair under the passed flags is cached.  The length of the signature script
+// is included so that different splits of the same bytes between the two
+// scripts have different keys.
+func scriptCacheKey(sigScript, pkScript []byte, flags ScriptFlags) chainhash.Hash {
+	buf := make([]byte, 0, 8+len(sigScript)+len(pkScript))
+	var scratch [4]byte
+	binary.LittleEndian.PutUint32(scratch[:], uint32(len(sigScript)))
+	buf = append(buf, scratch[:]...)
+	buf = append(buf, sigScript...)
+	buf = append(buf, pkScript...)
+	binary.LittleEndian.PutUint32(scratch[:], uint32(flags))
+	buf = append(buf, scratch[:]...)
+	return chainhash.HashH(buf)
+}
+
+// isDefinitiveScriptError returns whether the passed error, as returned by
+// NewEngine, is a script error which is fully determined by the signature
+// script, public key script and flags.
+func isDefinitiveScriptError(err error) bool {
+	serr, ok := err.(Error)
+	if !ok {
+		return false
+	}
+	switch serr.ErrorCode {
+	case ErrEvalFalse, ErrInvalidFlags, ErrNotPushOnly, ErrScriptTooBig,
+		ErrMalformedPush, ErrWitnessMalleated:
+		return true
+	}
+	return false
+}
+
+// Lookup returns the error of the passed script pair under the passed flags
+// when it is known to be invalid, or nil otherwise.
+//
+// NOTE: This function is safe for concurrent access. Readers won't be blocked
+// unless there exists a writer, adding an entry to the ScriptCache.
+func (c *ScriptCache) Lookup(sigScript, pkScript []byte, flags ScriptFlags) error {
+	key := scriptCacheKey(sigScript, pkScript, flags)
+	c.RLock()
+	entry, ok := c.invalid[key]
+	c.RUnlock()
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return nil
+	}
+	atomic.AddUint64(&c.hits, 1)
+	return entry.err
+}
+
+// Add records that the passed script pair is invalid under the passed flags
+// with the passed error, which must have been returned by NewEngine for it,
+// and returns whether it was added.  Errors which are not fully determined by
+// the scripts and flags are not added.  If adding the entry would make the
+// number of entries exceed the max, a random entry is evicted first.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (c *ScriptCache) Add(sigScript, pkScript []byte, flags ScriptFlags, err error) bool {
+	if !isDefinitiveScriptError(err) {
+		return false
+	}
+	key := scriptCacheKey(sigScript, pkScript, flags)
+
+	c.Lock()
+	defer c.Unlock()
+
+	if c.maxEntries <= 0 {
+		return false
+	}
+	if _, ok := c.invalid[key]; ok {
+		return true
+	}
+
+	// Remove a random entry from the cache to make room for the new one.
+	if victim, ok := c.evictList.evict(c.maxEntries); ok {
+		delete(c.invalid, victim)
+		atomic.AddUint64(&c.evictions, 1)
+	}
+
+	entry := &scriptCacheEntry{evictionEntry: evictionEntry{key: key},
+		err: err.(Error)}
+	c.invalid[key] = entry
+	c.evictList.add(&entry.evictionEntry)
+	return true
+}
+
+// Len returns the number of entries in the script cache.
+//
+// NOTE: This function is safe for concurrent access.
+func (c *ScriptCache) Len() int {
+	c.RLock()
+	n := len(c.evictList.entries)
+	c.RUnlock()
+	return n
+}
+
+// Stats returns the usage statistics of the script cache, where a hit is a
+// call to Lookup which found a cached failure.  Like SigCache.Stats, the
+// counters are read atomically without blocking writers.
+//
+// NOTE: This function is safe for concurrent access.
+func (c *ScriptCache) Stats() CacheStats {
+	c.RLock()
+	entries, maxEntries := len(c.evictList.entries), c.maxEntries
+	c.RUnlock()
+	return CacheStats{
+		Entries:    uint64(entries),
+		MaxEntries: uint64(maxEntries),
+		Hits:       atomic.LoadUint64(&c.hits),
+		Misses:     atomic.LoadUint64(&c.misses),
+		Evictions:  atomic.LoadUint64(&c.evictions),
+	}
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/navcoin/navd/wire"
+)
+
+// TestScriptCacheAddLookup tests that only definitive failures are added to a
+// script cache and that they are only found for the exact scripts and flags
+// they were added for.
+func TestScriptCacheAddLookup(t *testing.T) {
+	scriptCache := NewScriptCache(10)
+	sigScript := []byte{OP_1, OP_2}
+	pkScript := []byte{OP_3}
+	flags := ScriptBip16 | ScriptVerifySigPushOnly
+	cachedErr := scriptError(ErrNotPushOnly, "not push only")
+
+	if !scriptCache.Add(sigScript, pkScript, flags, cachedErr) {
+		t.Fatalf("Add: definitive failure was not added")
+	}
+	err := scriptCache.Lookup(sigScript, pkScript, flags)
+	if err != cachedErr {
+		t.Fatalf("Lookup: got error %v, want %v", err, cachedErr)
+	}
+
+	misses := []struct {
+		name      string
+		sigScript []byte
+		pkScript  []byte
+		flags     ScriptFlags
+	}{
+		{"different flags", sigScript, pkScript, ScriptBip16},
+		{"different sigScript", []byte{OP_1}, pkScript, flags},
+		{"different pkScript", sigScript, []byte{OP_4}, flags},
+		{"different split", []byte{OP_1}, []byte{OP_2, OP_3}, flags},
+	}
+	for _, test := range misses {
+		err := scriptCache.Lookup(test.sigScript, test.pkScript,
+			test.flags)
+		if err != nil {
+			t.Errorf("Lookup (%s): unexpected error %v", test.name,
+				err)
+		}
+	}
+
+	// Failures which may depend on the spending transaction must never be
+	// added.
+	for _, err := range []error{
+		scriptError(ErrWitnessUnexpected, "unexpected witness"),
+		scriptError(ErrWitnessMalleatedP2SH, "not canonical"),
+		scriptError(ErrCleanStack, "stack not clean"),
+		scriptError(ErrCheckSigVerify, "invalid signature"),
+		errors.New("not a script error"),
+		nil,
+	} {
+		if scriptCache.Add(sigScript, []byte{OP_5}, flags, err) {
+			t.Errorf("Add: non-definitive failure %v was added", err)
+		}
+	}
+	if n := scriptCache.Len(); n != 1 {
+		t.Fatalf("Len: script cache holds %d entries, want 1", n)
+	}
+}
+
+// TestScriptCacheEviction tests that a script cache holds at most its maximum
+// number of entries by evicting random ones.
+func TestScriptCacheEviction(t *testing.T) {
+	const maxEntries = 5
+	source := &fixedSource{values: []uint64{0, 3, 1, 4, 2}}
+	scriptCache := NewScriptCacheWithSource(maxEntries, source)
+	cachedErr := scriptError(ErrScriptTooBig, "too big")
+
+	for i := 0; i < 50; i++ {
+		pkScript := []byte{byte(i)}
+		if !scriptCache.Add(nil, pkScript, 0, cachedErr) {
+			t.Fatalf("Add #%d: definitive failure was not added", i)
+		}
+		if scriptCache.Lookup(nil, pkScript, 0) == nil {
+			t.Fatalf("Add #%d: added entry not found", i)
+		}
+
+		want := i + 1
+		if want > maxEntries {
+			want = maxEntries
+		}
+		if n := scriptCache.Len(); n != want {
+			t.Fatalf("Add #%d: script cache holds %d entries, want "+
+				"%d", i, n, want)
+		}
+		if len(scriptCache.invalid) != want {
+			t.Fatalf("Add #%d: script cache maps %d entries, want "+
+				"%d", i, len(scriptCache.invalid), want)
+		}
+		for j, entry := range scriptCache.evictList.entries {
+			if entry.index != j {
+				t.Fatalf("Add #%d: entry at %d has index %d", i,
+					j, entry.index)
+			}
+		}
+	}
+
+	// A script cache without room for any entries must not add any.
+	scriptCache = NewScriptCache(0)
+	if scriptCache.Add(nil, []byte{OP_1}, 0, cachedErr) {
+		t.Fatalf("Add: entry added to script cache without room")
+	}
+}
+
+// TestVerifyBlockScriptsScriptCache tests that VerifyBlockScripts records the
+// failures of invalid script pairs in the script cache and rejects inputs
+// spending cached script pairs without executing them.
+func TestVerifyBlockScriptsScriptCache(t *testing.T) {
+	const flags = ScriptBip16 | ScriptVerifyDERSignatures |
+		ScriptVerifyStrictEncoding
+
+	// A malformed public key script must be recorded.
+	txns, prevOuts := genVerifyTxns(t, 2)
+	badOut := txns[1].TxIn[0].PreviousOutPoint
+	prevOuts.outputs[badOut] = wire.NewTxOut(0, []byte{OP_PUSHDATA1})
+	scriptCache := NewScriptCache(10)
+	err := VerifyBlockScripts(txns, prevOuts, flags, nil, scriptCache,
+		nil, 1)
+	if !IsErrorCode(err, ErrMalformedPush) {
+		t.Fatalf("VerifyBlockScripts: got error %v, want %v", err,
+			ErrMalformedPush)
+	}
+	if n := scriptCache.Len(); n != 1 {
+		t.Fatalf("VerifyBlockScripts: script cache holds %d entries, "+
+			"want 1", n)
+	}
+
+	// An input spending a cached script pair must be rejected with the
+	// cached error even though its scripts are valid, which shows the
+	// engine was never run, but only under the cached flags.
+	txns, prevOuts = genVerifyTxns(t, 2)
+	txIn := txns[2].TxIn[0]
+	pkScript := prevOuts.outputs[txIn.PreviousOutPoint].PkScript
+	scriptCache.Add(txIn.SignatureScript, pkScript, flags,
+		scriptError(ErrNotPushOnly, "cached failure"))
+	err = VerifyBlockScripts(txns, prevOuts, flags, nil, scriptCache,
+		nil, 1)
+	if !IsErrorCode(err, ErrNotPushOnly) {
+		t.Fatalf("VerifyBlockScripts: got error %v, want %v", err,
+			ErrNotPushOnly)
+	}
+	err = VerifyBlockScripts(txns, prevOuts, flags|ScriptVerifyLowS, nil,
+		scriptCache, nil, 1)
+	if err != nil {
+		t.Fatalf("VerifyBlockScripts (other flags): unexpected error: %v",
+			err)
+	}
+}
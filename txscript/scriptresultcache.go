@@ -0,0 +1,185 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+
+	"github.com/navcoin/navd/chaincfg/chainhash"
+	"github.com/navcoin/navd/wire"
+)
+
+// scriptResultEntry represents the verdict of executing the scripts of a
+// transaction input.
+type scriptResultEntry struct {
+	evictionEntry
+	valid bool
+}
+
+// ScriptResultCache implements a cache of the verdicts of executing the scripts
+// of transaction inputs, with a randomized entry eviction policy like SigCache.
+// Each verdict is stored under a key returned by ScriptResultKey, which covers
+// everything the execution depends on, including the script flags, so that a
+// verdict is never reused for a different transaction or different consensus
+// rules.  This allows the inputs of transactions which were already verified in
+// the mempool to be accepted in a block without running the script engine
+// again.
+type ScriptResultCache struct {
+	// The following variables must only be used atomically.  They are
+	// placed first to ensure 64-bit alignment on 32-bit platforms.
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
+	sync.RWMutex
+	results    map[chainhash.Hash]*scriptResultEntry
+	maxEntries uint
+
+	// evictList holds every entry in the cache so a random one can be
+	// evicted.  It is only accessed with the write lock held.
+	evictList evictionList
+}
+
+// NewScriptResultCache creates and initializes a new instance of
+// ScriptResultCache.  Its sole parameter 'maxEntries' represents the maximum
+// number of entries allowed to exist in the ScriptResultCache at any particular
+// moment.  Random entries are evicted to make room for new entries that would
+// cause the number of entries in the cache to exceed the max.
+func NewScriptResultCache(maxEntries uint) *ScriptResultCache {
+	return NewScriptResultCacheWithSource(maxEntries, newEvictionSource())
+}
+
+// NewScriptResultCacheWithSource creates and initializes a new instance of
+// ScriptResultCache which chooses the entry to evict using the provided source
+// of randomness.  Like NewSigCacheWithSource, it is primarily useful for tests
+// and callers must ensure the source is securely seeded when the cache is used
+// for validation.
+func NewScriptResultCacheWithSource(maxEntries uint, source rand.Source64) *ScriptResultCache {
+	sizeHint := cacheSizeHint(maxEntries)
+	return &ScriptResultCache{
+		results:    make(map[chainhash.Hash]*scriptResultEntry, sizeHint),
+		maxEntries: maxEntries,
+		evictList:  evictionList{randSource: source},
+	}
+}
+
+// ScriptResultKey returns the key under which the verdict of executing the
+// scripts of the passed transaction input, which spends an output with the
+// passed public key script and amount, under the passed flags is cached.
+//
+// The key commits to the signature script, witness, index and amount of the
+// input, the public key script, the flags, and a SigHashAll signature hash over
+// the public key script, which commits to the rest of the transaction apart
+// from the scripts of its other inputs.  An error is returned when the public
+// key script does not parse, since such inputs never execute.
+func ScriptResultKey(tx *wire.MsgTx, txIdx int, pkScript []byte, amount int64,
+	flags ScriptFlags) (chainhash.Hash, error) {
+
+	sigHash, err := CalcSignatureHash(pkScript, SigHashAll, tx, txIdx)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	txIn := tx.TxIn[txIdx]
+	var buf bytes.Buffer
+	writeItem := func(b []byte) {
+		wire.WriteVarBytes(&buf, 0, b)
+	}
+	writeItem(txIn.SignatureScript)
+	writeItem(pkScript)
+	wire.WriteVarInt(&buf, 0, uint64(len(txIn.Witness)))
+	for _, item := range txIn.Witness {
+		writeItem(item)
+	}
+	buf.Write(sigHash[:])
+	var scratch [8]byte
+	binary.LittleEndian.PutUint32(scratch[:4], uint32(txIdx))
+	buf.Write(scratch[:4])
+	binary.LittleEndian.PutUint64(scratch[:], uint64(amount))
+	buf.Write(scratch[:])
+	binary.LittleEndian.PutUint32(scratch[:4], uint32(flags))
+	buf.Write(scratch[:4])
+	return chainhash.HashH(buf.Bytes()), nil
+}
+
+// Lookup returns the verdict cached under the passed key, which is true when
+// the scripts were found to be valid, and whether there is one.
+//
+// NOTE: This function is safe for concurrent access. Readers won't be blocked
+// unless there exists a writer, adding an entry to the ScriptResultCache.
+func (c *ScriptResultCache) Lookup(key chainhash.Hash) (valid, ok bool) {
+	c.RLock()
+	entry, ok := c.results[key]
+	c.RUnlock()
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		return false, false
+	}
+	atomic.AddUint64(&c.hits, 1)
+	return entry.valid, true
+}
+
+// Add caches the passed verdict under the passed key, which must have been
+// returned by ScriptResultKey, replacing any verdict already cached under it.
+// If adding the entry would make the number of entries exceed the max, a
+// random entry is evicted first.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (c *ScriptResultCache) Add(key chainhash.Hash, valid bool) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.maxEntries <= 0 {
+		return
+	}
+	if entry, ok := c.results[key]; ok {
+		entry.valid = valid
+		return
+	}
+
+	// Remove a random entry from the cache to make room for the new one.
+	if victim, ok := c.evictList.evict(c.maxEntries); ok {
+		delete(c.results, victim)
+		atomic.AddUint64(&c.evictions, 1)
+	}
+
+	entry := &scriptResultEntry{evictionEntry: evictionEntry{key: key},
+		valid: valid}
+	c.results[key] = entry
+	c.evictList.add(&entry.evictionEntry)
+}
+
+// Len returns the number of entries in the script result cache.
+//
+// NOTE: This function is safe for concurrent access.
+func (c *ScriptResultCache) Len() int {
+	c.RLock()
+	n := len(c.evictList.entries)
+	c.RUnlock()
+	return n
+}
+
+// Stats returns the usage statistics of the script result cache, where a hit is
+// a call to Lookup which found a cached verdict, whether valid or not.  Like
+// SigCache.Stats, the counters are read atomically without blocking writers.
+//
+// NOTE: This function is safe for concurrent access.
+func (c *ScriptResultCache) Stats() CacheStats {
+	c.RLock()
+	entries, maxEntries := len(c.evictList.entries), c.maxEntries
+	c.RUnlock()
+	return CacheStats{
+		Entries:    uint64(entries),
+		MaxEntries: uint64(maxEntries),
+		Hits:       atomic.LoadUint64(&c.hits),
+		Misses:     atomic.LoadUint64(&c.misses),
+		Evictions:  atomic.LoadUint64(&c.evictions),
+	}
+}
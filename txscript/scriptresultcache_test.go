@@ -0,0 +1,192 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"testing"
+
+	"github.com/navcoin/navd/chaincfg/chainhash"
+	"github.com/navcoin/navd/wire"
+)
+
+// TestScriptResultKey ensures the script result key of a transaction input
+// changes with everything the execution of its scripts depends on.
+func TestScriptResultKey(t *testing.T) {
+	const flags = ScriptBip16 | ScriptVerifyDERSignatures
+	pkScript := []byte{OP_TRUE}
+	newTx := func() *wire.MsgTx {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		for i := 0; i < 2; i++ {
+			prevOut := wire.NewOutPoint(&chainhash.Hash{0x01}, uint32(i))
+			tx.AddTxIn(wire.NewTxIn(prevOut, []byte{OP_1}, nil))
+		}
+		tx.AddTxOut(wire.NewTxOut(1, pkScript))
+		return tx
+	}
+
+	baseKey, err := ScriptResultKey(newTx(), 0, pkScript, 1, flags)
+	if err != nil {
+		t.Fatalf("ScriptResultKey: unexpected error: %v", err)
+	}
+	key, err := ScriptResultKey(newTx(), 0, pkScript, 1, flags)
+	if err != nil || key != baseKey {
+		t.Fatalf("ScriptResultKey: key of identical input differs")
+	}
+
+	tests := []struct {
+		name     string
+		modify   func(tx *wire.MsgTx)
+		txIdx    int
+		pkScript []byte
+		amount   int64
+		flags    ScriptFlags
+	}{
+		{"flags", nil, 0, pkScript, 1, flags | ScriptVerifyLowS},
+		{"no flags", nil, 0, pkScript, 1, 0},
+		{"input index", nil, 1, pkScript, 1, flags},
+		{"amount", nil, 0, pkScript, 2, flags},
+		{"pkScript", nil, 0, []byte{OP_TRUE, OP_NOP}, 1, flags},
+		{"sigScript", func(tx *wire.MsgTx) {
+			tx.TxIn[0].SignatureScript = []byte{OP_2}
+		}, 0, pkScript, 1, flags},
+		{"witness", func(tx *wire.MsgTx) {
+			tx.TxIn[0].Witness = wire.TxWitness{{0x01}}
+		}, 0, pkScript, 1, flags},
+		{"outputs", func(tx *wire.MsgTx) {
+			tx.TxOut[0].Value = 2
+		}, 0, pkScript, 1, flags},
+		{"lock time", func(tx *wire.MsgTx) {
+			tx.LockTime = 1
+		}, 0, pkScript, 1, flags},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		tx := newTx()
+		if test.modify != nil {
+			test.modify(tx)
+		}
+		key, err := ScriptResultKey(tx, test.txIdx, test.pkScript,
+			test.amount, test.flags)
+		if err != nil {
+			t.Errorf("ScriptResultKey (%s): unexpected error: %v",
+				test.name, err)
+			continue
+		}
+		if key == baseKey {
+			t.Errorf("ScriptResultKey (%s): key did not change",
+				test.name)
+		}
+	}
+
+	// Public key scripts which don't parse have no key.
+	_, err = ScriptResultKey(newTx(), 0, []byte{OP_PUSHDATA1}, 1, flags)
+	if !IsErrorCode(err, ErrMalformedPush) {
+		t.Fatalf("ScriptResultKey: got error %v, want %v", err,
+			ErrMalformedPush)
+	}
+}
+
+// TestScriptResultCacheEviction tests that a script result cache holds at most
+// its maximum number of entries by evicting random ones.
+func TestScriptResultCacheEviction(t *testing.T) {
+	const maxEntries = 5
+	source := &fixedSource{values: []uint64{4, 0, 2, 1, 3}}
+	resultCache := NewScriptResultCacheWithSource(maxEntries, source)
+
+	for i := 0; i < 50; i++ {
+		key := chainhash.Hash{byte(i)}
+		resultCache.Add(key, i%2 == 0)
+		if valid, ok := resultCache.Lookup(key); !ok || valid != (i%2 == 0) {
+			t.Fatalf("Add #%d: got verdict %v (found %v), want %v", i,
+				valid, ok, i%2 == 0)
+		}
+
+		want := i + 1
+		if want > maxEntries {
+			want = maxEntries
+		}
+		if n := resultCache.Len(); n != want {
+			t.Fatalf("Add #%d: script result cache holds %d entries, "+
+				"want %d", i, n, want)
+		}
+		for j, entry := range resultCache.evictList.entries {
+			if entry.index != j {
+				t.Fatalf("Add #%d: entry at %d has index %d", i,
+					j, entry.index)
+			}
+		}
+	}
+
+	// Adding a verdict under a cached key replaces it.
+	key := chainhash.Hash{48}
+	resultCache.Add(key, false)
+	if valid, ok := resultCache.Lookup(key); !ok || valid {
+		t.Fatalf("Add: verdict was not replaced")
+	}
+	if n := resultCache.Len(); n != maxEntries {
+		t.Fatalf("Add: script result cache holds %d entries, want %d",
+			n, maxEntries)
+	}
+
+	// A script result cache without room for any entries must not add any.
+	resultCache = NewScriptResultCache(0)
+	resultCache.Add(key, true)
+	if _, ok := resultCache.Lookup(key); ok {
+		t.Fatalf("Add: entry added to script result cache without room")
+	}
+}
+
+// TestVerifyBlockScriptsResultCache tests that VerifyBlockScripts records the
+// valid inputs in the script result cache and skips the script engine for
+// inputs with a cached valid verdict, but only under the same flags.
+func TestVerifyBlockScriptsResultCache(t *testing.T) {
+	const numTxns = 10
+	const flags = ScriptBip16 | ScriptVerifyDERSignatures |
+		ScriptVerifyStrictEncoding
+
+	txns, prevOuts := genVerifyTxns(t, numTxns)
+	resultCache := NewScriptResultCache(numTxns * 2)
+	err := VerifyBlockScripts(txns, prevOuts, flags, nil, nil,
+		resultCache, 4)
+	if err != nil {
+		t.Fatalf("VerifyBlockScripts: unexpected error: %v", err)
+	}
+	if n := resultCache.Len(); n != numTxns {
+		t.Fatalf("VerifyBlockScripts: script result cache holds %d "+
+			"entries, want %d", n, numTxns)
+	}
+
+	// Make the first input invalid while caching a valid verdict for it.
+	// It must then only be accepted under the cached flags, since that is
+	// only possible when the engine is skipped.
+	txIn := txns[1].TxIn[0]
+	badOut := wire.NewTxOut(0, []byte{OP_FALSE})
+	prevOuts.outputs[txIn.PreviousOutPoint] = badOut
+	key, err := ScriptResultKey(txns[1], 0, badOut.PkScript, badOut.Value,
+		flags)
+	if err != nil {
+		t.Fatalf("ScriptResultKey: unexpected error: %v", err)
+	}
+	resultCache.Add(key, true)
+	err = VerifyBlockScripts(txns, prevOuts, flags, nil, nil, resultCache, 1)
+	if err != nil {
+		t.Fatalf("VerifyBlockScripts: unexpected error: %v", err)
+	}
+	err = VerifyBlockScripts(txns, prevOuts, flags|ScriptVerifyLowS, nil,
+		nil, resultCache, 1)
+	if !IsErrorCode(err, ErrEvalFalse) {
+		t.Fatalf("VerifyBlockScripts (other flags): got error %v, want "+
+			"%v", err, ErrEvalFalse)
+	}
+
+	// An invalid verdict must not prevent the engine from running.
+	resultCache.Add(key, false)
+	err = VerifyBlockScripts(txns, prevOuts, flags, nil, nil, resultCache, 1)
+	if !IsErrorCode(err, ErrEvalFalse) {
+		t.Fatalf("VerifyBlockScripts (invalid verdict): got error %v, "+
+			"want %v", err, ErrEvalFalse)
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"encoding/binary"
+
+	"github.com/navcoin/navd/btcec"
+	"github.com/navcoin/navd/chaincfg/chainhash"
+)
+
+// ShardedSigCache is a signature verification cache which splits its entries
+// across several independent SigCache instances, each with its own lock.
+// Entries are routed to a shard according to their sigHash, so concurrent
+// additions of different signatures, such as those made while validating the
+// scripts of a block in parallel, are unlikely to contend for the same lock.
+type ShardedSigCache struct {
+	shards []*SigCache
+}
+
+// Ensure ShardedSigCache and SigCache implement the SignatureCache interface.
+var _ SignatureCache = (*ShardedSigCache)(nil)
+var _ SignatureCache = (*SigCache)(nil)
+
+// NewShardedSigCache creates and initializes a new instance of ShardedSigCache
+// which holds up to 'maxEntries' entries in total, divided as evenly as
+// possible across the requested number of shards.  The shard count is limited
+// to the range from one to 'maxEntries' so that every shard is able to hold at
+// least one entry.  Each shard evicts random entries independently, so an entry
+// may be evicted while other shards still have room.
+func NewShardedSigCache(maxEntries uint, shards int) *ShardedSigCache {
+	switch {
+	case shards < 1 || maxEntries == 0:
+		shards = 1
+	case uint(shards) > maxEntries:
+		shards = int(maxEntries)
+	}
+
+	perShard := maxEntries / uint(shards)
+	remainder := maxEntries % uint(shards)
+	s := &ShardedSigCache{shards: make([]*SigCache, shards)}
+	for i := range s.shards {
+		shardEntries := perShard
+		if uint(i) < remainder {
+			shardEntries++
+		}
+		s.shards[i] = NewSigCache(shardEntries)
+	}
+	return s
+}
+
+// shard returns the shard responsible for the passed sigHash.  Since sigHashes
+// are uniformly distributed, the first bytes are sufficient for routing.
+func (s *ShardedSigCache) shard(sigHash *chainhash.Hash) *SigCache {
+	idx := binary.LittleEndian.Uint32(sigHash[:4]) % uint32(len(s.shards))
+	return s.shards[idx]
+}
+
+// Exists returns true if an existing entry of 'sig' over 'sigHash' for public
+// key 'pubKey' is found within the cache. Otherwise, false is returned.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) Exists(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) bool {
+	return s.shard(&sigHash).Exists(sigHash, sig, pubKey)
+}
+
+// Add adds an entry for a signature over 'sigHash' under public key 'pubKey'
+// to the shard responsible for the sigHash, evicting a random entry from that
+// shard when it is full.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) Add(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) {
+	s.shard(&sigHash).Add(sigHash, sig, pubKey)
+}
+
+// AddWithFlags adds an entry for a signature over 'sigHash' under public key
+// 'pubKey' to the shard responsible for the sigHash while providing hints about
+// the entry through the passed flags.  See SigCache.AddWithFlags for details.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) AddWithFlags(sigHash chainhash.Hash, sig *btcec.Signature,
+	pubKey *btcec.PublicKey, flags SigCacheFlags) {
+
+	s.shard(&sigHash).AddWithFlags(sigHash, sig, pubKey, flags)
+}
+
+// ExistsSchnorr returns true if an existing entry of the serialized Schnorr
+// signature 'sig' over 'sigHash' for the serialized public key 'pubKey' is
+// found within the shard responsible for the sigHash.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) ExistsSchnorr(sigHash chainhash.Hash, sig, pubKey []byte) bool {
+	return s.shard(&sigHash).ExistsSchnorr(sigHash, sig, pubKey)
+}
+
+// AddSchnorr adds an entry for the serialized Schnorr signature 'sig' over
+// 'sigHash' under the serialized public key 'pubKey' to the shard responsible
+// for the sigHash.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) AddSchnorr(sigHash chainhash.Hash, sig, pubKey []byte) {
+	s.shard(&sigHash).AddSchnorr(sigHash, sig, pubKey)
+}
+
+// ExistsOrAdd returns true if an entry of 'sig' over 'sigHash' for public key
+// 'pubKey' is found within the cache.  Otherwise, the passed verify function is
+// invoked and the signature is added to the shard responsible for the sigHash
+// when it is valid.  See SigCache.ExistsOrAdd for details.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) ExistsOrAdd(sigHash chainhash.Hash, sig *btcec.Signature,
+	pubKey *btcec.PublicKey, verify func() bool) bool {
+
+	return s.shard(&sigHash).ExistsOrAdd(sigHash, sig, pubKey, verify)
+}
+
+// Remove removes the entry for 'sigHash' from the cache, if any, and returns
+// whether an entry was removed.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) Remove(sigHash chainhash.Hash) bool {
+	return s.shard(&sigHash).Remove(sigHash)
+}
+
+// Len returns the total number of entries across all shards.  Since the shards
+// are not locked together, the result is only an approximate snapshot when the
+// cache is concurrently modified.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) Len() int {
+	var n int
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// IsFull returns whether adding an entry for a new sigHash could cause an
+// existing entry to be evicted, which is the case as soon as any of the shards
+// is full since the shard a new entry is routed to depends on its sigHash.
+// Like Len, the result is only a point-in-time snapshot.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) IsFull() bool {
+	for _, shard := range s.shards {
+		if shard.IsFull() {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the usage statistics of the cache summed across all shards.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) Stats() SigCacheStats {
+	var stats SigCacheStats
+	for _, shard := range s.shards {
+		shardStats := shard.Stats()
+		stats.Hits += shardStats.Hits
+		stats.Misses += shardStats.Misses
+		stats.Evictions += shardStats.Evictions
+		stats.Entries += shardStats.Entries
+	}
+	return stats
+}
+
+// ResetStats resets the hit, miss, and eviction counters of all shards.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *ShardedSigCache) ResetStats() {
+	for _, shard := range s.shards {
+		shard.ResetStats()
+	}
+}
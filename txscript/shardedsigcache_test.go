@@ -0,0 +1,91 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"testing"
+
+	"github.com/navcoin/navd/chaincfg/chainhash"
+)
+
+// TestShardedSigCacheSizing ensures the max number of entries is divided
+// across the shards without losing any capacity.
+func TestShardedSigCacheSizing(t *testing.T) {
+	tests := []struct {
+		maxEntries uint
+		shards     int
+		wantShards int
+	}{
+		{maxEntries: 100, shards: 4, wantShards: 4},
+		{maxEntries: 101, shards: 4, wantShards: 4},
+		{maxEntries: 3, shards: 8, wantShards: 3},
+		{maxEntries: 0, shards: 8, wantShards: 1},
+		{maxEntries: 100, shards: 0, wantShards: 1},
+		{maxEntries: 100, shards: -2, wantShards: 1},
+	}
+
+	for i, test := range tests {
+		sigCache := NewShardedSigCache(test.maxEntries, test.shards)
+		if len(sigCache.shards) != test.wantShards {
+			t.Errorf("test #%d: got %d shards, want %d", i,
+				len(sigCache.shards), test.wantShards)
+			continue
+		}
+		var total uint
+		for j, shard := range sigCache.shards {
+			if shard.maxEntries == 0 && test.maxEntries != 0 {
+				t.Errorf("test #%d: shard #%d is disabled", i, j)
+			}
+			total += shard.maxEntries
+		}
+		if total != test.maxEntries {
+			t.Errorf("test #%d: shards hold %d entries, want %d", i,
+				total, test.maxEntries)
+		}
+	}
+}
+
+// TestShardedSigCacheAddExists tests that entries added to a sharded signature
+// cache are found, are routed to a single shard, and are reflected in the
+// aggregate length and stats.
+func TestShardedSigCacheAddExists(t *testing.T) {
+	sigCache := NewShardedSigCache(100, 4)
+
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	// Use sigHashes which route to each of the shards.
+	sigHashes := make([]chainhash.Hash, 8)
+	for i := range sigHashes {
+		sigHashes[i][0] = byte(i)
+		sigCache.Add(sigHashes[i], sig, key)
+	}
+	for i, sigHash := range sigHashes {
+		if !sigCache.Exists(sigHash, sig, key) {
+			t.Fatalf("entry #%d not found in signature cache", i)
+		}
+	}
+	for i, shard := range sigCache.shards {
+		if n := shard.Len(); n != 2 {
+			t.Errorf("shard #%d has %d entries, want 2", i, n)
+		}
+	}
+
+	if !sigCache.Remove(sigHashes[0]) {
+		t.Fatalf("Remove: existing entry was not removed")
+	}
+	if n := sigCache.Len(); n != len(sigHashes)-1 {
+		t.Fatalf("Len: got %d, want %d", n, len(sigHashes)-1)
+	}
+	if sigCache.IsFull() {
+		t.Fatalf("IsFull: cache with room in every shard is full")
+	}
+	want := SigCacheStats{Hits: 8, Entries: 7}
+	if stats := sigCache.Stats(); stats != want {
+		t.Fatalf("unexpected stats - got %+v, want %+v", stats, want)
+	}
+}
@@ -5,37 +5,312 @@
 package txscript
 
 import (
+	"bytes"
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"math"
+	"math/rand"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/navcoin/navd/btcec"
 	"github.com/navcoin/navd/chaincfg/chainhash"
 )
 
+// The following constants are used to estimate the memory footprint of the
+// entries in a SigCache created with NewSigCacheBytes.  They are based on
+// the in-memory sizes of the involved types on 64-bit platforms and are only
+// meant to be a reasonable approximation.  In particular, they don't account
+// for allocator rounding or the temporary memory used while the map grows.
+const (
+	// sigCacheMapEntryBytes is the approximate overhead of an entry in the
+	// map of valid signatures, including the 32-byte sigHash key, the
+	// 24-byte header of the slice of entries for the sigHash along with
+	// its single element backing array in the common case, and an
+	// allowance for bucket overhead and unused bucket slots.
+	sigCacheMapEntryBytes = 96
+
+	// sigCacheEntryBytes is the size of a sigCacheEntry struct.
+	sigCacheEntryBytes = 136
+
+	// sigCacheSignatureBytes is the approximate size of a parsed
+	// btcec.Signature, which consists of two big.Int values each with a
+	// 32-byte backing array.
+	sigCacheSignatureBytes = 144
+
+	// sigCachePubKeyBytes is the approximate size of a parsed
+	// btcec.PublicKey, which consists of a curve interface value and two
+	// big.Int values each with a 32-byte backing array.
+	sigCachePubKeyBytes = 160
+
+	// sigCacheBytesPerEntry is the approximate total memory used by a
+	// single entry in a SigCache.
+	sigCacheBytesPerEntry = sigCacheMapEntryBytes + sigCacheEntryBytes +
+		sigCacheSignatureBytes + sigCachePubKeyBytes
+)
+
+// maxCachePreallocEntries is the maximum number of entries the maps of the
+// caches in this package preallocate room for on creation.  Preallocating
+// avoids repeatedly growing the maps while the caches fill up, such as during
+// the initial mempool fill, while the cap avoids reserving an excessive amount
+// of memory up front for very large limits which may never be reached.
+const maxCachePreallocEntries = 1 << 17
+
+// cacheSizeHint returns the size hint to create the map of a cache holding at
+// most the passed number of entries with.
+func cacheSizeHint(maxEntries uint) int {
+	if maxEntries > maxCachePreallocEntries {
+		return maxCachePreallocEntries
+	}
+	return int(maxEntries)
+}
+
+// SignatureCache is the interface implemented by the signature verification
+// caches which may be used by the script engine and the validators built on top
+// of it, such as SigCache and ShardedSigCache.  Implementations must only
+// report signatures as existing which were previously added, and must be safe
+// for concurrent access since scripts may be validated in parallel.
+type SignatureCache interface {
+	// Exists returns true if an existing entry of 'sig' over 'sigHash'
+	// for public key 'pubKey' is found within the cache.
+	Exists(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) bool
+
+	// Add adds an entry for a signature over 'sigHash' under public key
+	// 'pubKey' to the cache.
+	Add(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey)
+}
+
+// NopSigCache is a SignatureCache which never caches anything.  Exists always
+// returns false and Add does nothing, so every signature is verified.  It is
+// useful for callers that want to disable caching entirely, such as tools that
+// only verify or benchmarks that isolate the cost of signature verification.
+type NopSigCache struct{}
+
+// Ensure NopSigCache implements the SignatureCache interface.
+var _ SignatureCache = NopSigCache{}
+
+// Exists always returns false.
+//
+// This is part of the SignatureCache interface.
+func (NopSigCache) Exists(chainhash.Hash, *btcec.Signature, *btcec.PublicKey) bool {
+	return false
+}
+
+// Add does nothing.
+//
+// This is part of the SignatureCache interface.
+func (NopSigCache) Add(chainhash.Hash, *btcec.Signature, *btcec.PublicKey) {}
+
+// normalizeSigCache returns the passed signature cache, or NopSigCache when it
+// is nil.  This includes nil pointers of the caches in this package stored in
+// the interface, such as a nil *SigCache passed by a caller which treats its
+// cache as optional, since calling their methods would otherwise panic.
+func normalizeSigCache(sigCache SignatureCache) SignatureCache {
+	switch c := sigCache.(type) {
+	case nil:
+		return NopSigCache{}
+	case *SigCache:
+		if c == nil {
+			return NopSigCache{}
+		}
+	case *ShardedSigCache:
+		if c == nil {
+			return NopSigCache{}
+		}
+	}
+	return sigCache
+}
+
+// sigCacheSigType identifies the signature scheme of an entry in the SigCache
+// so that entries for different schemes never match one another.
+type sigCacheSigType uint8
+
+const (
+	// sigTypeECDSA identifies entries holding a parsed ECDSA signature and
+	// public key.
+	sigTypeECDSA sigCacheSigType = iota
+
+	// sigTypeSchnorr identifies entries holding a raw Schnorr signature
+	// and public key.
+	sigTypeSchnorr
+)
+
+// SigCacheFlags is a bitmask of hints which may be passed when adding an entry
+// to a SigCache with AddWithFlags.
+type SigCacheFlags uint8
+
+const (
+	// SigCacheEphemeral marks an entry as unlikely to be checked again,
+	// such as a signature from a transaction which is not expected to be
+	// included in a block.  Ephemeral entries are evicted before any other
+	// entries.
+	SigCacheEphemeral SigCacheFlags = 1 << iota
+)
+
 // sigCacheEntry represents an entry in the SigCache. Entries within the
 // SigCache are keyed according to the sigHash of the signature. In the
 // scenario of a cache-hit (according to the sigHash), an additional comparison
 // of the signature, and public key will be executed in order to ensure a complete
-// match. In the occasion that two sigHashes collide, the newer sigHash will
-// simply overwrite the existing entry.
+// match. Since the same sigHash may be signed by several keys, such as in the
+// case of multisig scripts, multiple entries may exist under a single sigHash.
 type sigCacheEntry struct {
+	// sig and pubKey are only set for ECDSA entries.
 	sig    *btcec.Signature
 	pubKey *btcec.PublicKey
+
+	// raw holds the serialized signature followed by the serialized
+	// public key of Schnorr entries, and sigLen is the length of the
+	// signature within it.
+	raw     []byte
+	sigLen  int
+	sigType sigCacheSigType
+
+	// keyTag is a cheap discriminator derived from the public key of ECDSA
+	// entries by pubKeyTag.  Entries for the same key always have the same
+	// tag, so comparing it first avoids a full comparison of the keys in
+	// the common case of entries for different keys over the same sigHash.
+	// It fits in padding, so it doesn't increase the size of an entry.
+	keyTag uint8
+
+	// flags are the hints the entry was added with.
+	flags SigCacheFlags
+
+	// pinned is set while the sigHash of the entry is pinned with PinAll.
+	// Pinned entries are not tracked as eviction candidates.
+	pinned bool
+
+	// sigHash is the key of the entry in the cache.
+	sigHash chainhash.Hash
+
+	// added is the time the entry was added to the cache.  It is only set
+	// by caches created with NewSigCacheTTL.
+	added time.Time
+
+	// index is the position of the entry in the slice of entries used to
+	// choose a random entry to evict, which is the slice of ephemeral
+	// entries for entries added with SigCacheEphemeral.  Other entries of
+	// caches created with NewSigCacheLRU don't use it.
+	index int
+
+	// prev and next are only used by caches created with NewSigCacheLRU.
+	// They link the entry into the recency list so the least recently
+	// used entry can be located and evicted in constant time.  Keeping
+	// them in the shared entry type costs caches which evict random
+	// entries 16 otherwise unused bytes per entry, which is small next to
+	// the parsed signature and public key referenced by every entry.
+	prev, next *sigCacheEntry
 }
 
-// SigCache implements an ECDSA signature verification cache with a randomized
-// entry eviction policy. Only valid signatures will be added to the cache. The
-// benefits of SigCache are two fold. Firstly, usage of SigCache mitigates a DoS
-// attack wherein an attack causes a victim's client to hang due to worst-case
-// behavior triggered while processing attacker crafted invalid transactions. A
-// detailed description of the mitigated DoS attack can be found here:
+// matches returns whether the passed entry is for the same signature and public
+// key as the entry, which requires both to be of the same signature scheme.
+func (e *sigCacheEntry) matches(other *sigCacheEntry) bool {
+	if e.sigType != other.sigType {
+		return false
+	}
+	if e.sigType == sigTypeSchnorr {
+		return e.sigLen == other.sigLen && bytes.Equal(e.raw, other.raw)
+	}
+	if e.sig == nil || e.pubKey == nil || other.sig == nil ||
+		other.pubKey == nil {
+
+		return false
+	}
+	return e.keyTag == other.keyTag && e.pubKey.IsEqual(other.pubKey) &&
+		e.sig.IsEqual(other.sig)
+}
+
+// newECDSAEntry returns a new ECDSA entry for the passed signature and public
+// key with the passed flags.
+func newECDSAEntry(sig *btcec.Signature, pubKey *btcec.PublicKey, flags SigCacheFlags) *sigCacheEntry {
+	return &sigCacheEntry{sig: sig, pubKey: pubKey, flags: flags,
+		keyTag: pubKeyTag(pubKey)}
+}
+
+// pubKeyTag returns the discriminator of the passed public key stored in the
+// keyTag of ECDSA entries, which is the least significant byte of its x
+// coordinate.  It is computed without serializing the key or allocating.
+func pubKeyTag(pubKey *btcec.PublicKey) uint8 {
+	if pubKey == nil || pubKey.X == nil {
+		return 0
+	}
+	words := pubKey.X.Bits()
+	if len(words) == 0 {
+		return 0
+	}
+	return uint8(words[0])
+}
+
+// SigCache implements an ECDSA and Schnorr signature verification cache with a
+// randomized entry eviction policy. Only valid signatures will be added to the
+// cache. The benefits of SigCache are two fold. Firstly, usage of SigCache
+// mitigates a DoS attack wherein an attack causes a victim's client to hang due
+// to worst-case behavior triggered while processing attacker crafted invalid
+// transactions. A detailed description of the mitigated DoS attack can be found here:
 // https://bitslog.wordpress.com/2013/01/23/fixed-navcoin-vulnerability-explanation-why-the-signature-cache-is-a-dos-protection/.
 // Secondly, usage of the SigCache introduces a signature verification
 // optimization which speeds up the validation of transactions within a block,
 // if they've already been seen and verified within the mempool.
+//
+// A SigCache created with NewSigCacheLRU evicts the least recently used entry
+// instead of a random one.
 type SigCache struct {
+	// The following variables must only be used atomically.  They are
+	// placed first to ensure 64-bit alignment on 32-bit platforms.
+	hits      uint64
+	misses    uint64
+	evictions uint64
+
 	sync.RWMutex
-	validSigs  map[chainhash.Hash]sigCacheEntry
+	validSigs  map[chainhash.Hash][]*sigCacheEntry
+	numEntries uint
 	maxEntries uint
+
+	// randSource provides the randomness used to choose which entry is
+	// evicted from entries, which holds every entry in the cache that was
+	// not added with SigCacheEphemeral in no particular order.  Entries is
+	// only used by caches which evict random entries.  Both are only
+	// accessed with the write lock held.
+	randSource rand.Source64
+	entries    []*sigCacheEntry
+
+	// ephemeral holds the entries added with SigCacheEphemeral, which are
+	// not in entries.  A random one of them is evicted before any other
+	// entry regardless of the eviction policy of the cache.
+	ephemeral []*sigCacheEntry
+
+	// pending tracks the signatures which are currently being verified by
+	// ExistsOrAdd.  It is lazily created.
+	pending map[chainhash.Hash]*sigVerifyCall
+
+	// pins holds the number of outstanding PinAll calls for each pinned
+	// sigHash.  It is lazily created.
+	pins map[chainhash.Hash]int
+
+	// ttl is the duration after which entries are treated as absent, or
+	// zero when entries never expire.  now returns the current time and
+	// is only replaced by tests.
+	ttl time.Duration
+	now func() time.Time
+
+	// invalid holds the signatures which are known to be invalid when
+	// enabled with NewSigCacheWithInvalid.  It is a separate cache with
+	// its own budget so that invalid signatures never cause valid ones to
+	// be evicted.  It is set on creation and never changed.
+	invalid *SigCache
+
+	// lru is set when the cache evicts the least recently used entry.  In
+	// that case head and tail are the most and least recently used entries
+	// respectively.
+	lru  bool
+	head *sigCacheEntry
+	tail *sigCacheEntry
+
+	// evictBatch is the number of entries evicted at once when an addition
+	// finds the cache full, which allows the cache to exceed maxEntries by
+	// up to one less than it.  Values of zero and one evict a single entry
+	// per addition.  It is set on creation and never changed.
+	evictBatch uint
 }
 
 // NewSigCache creates and initializes a new instance of SigCache. Its sole
@@ -43,57 +318,892 @@ type SigCache struct {
 // exist in the SigCache at any particular moment. Random entries are evicted
 // to make room for new entries that would cause the number of entries in the
 // cache to exceed the max.
+//
+// A 'maxEntries' of zero creates a valid, fully disabled cache: adding entries
+// does nothing and allocates no memory, no entry is ever found, and Len is
+// always zero.  Callers may therefore use a zero-sized cache in place of one
+// which is turned off without guarding the calls to it.
 func NewSigCache(maxEntries uint) *SigCache {
+	return NewSigCacheWithSource(maxEntries, newEvictionSource())
+}
+
+// newEvictionSource returns a source of randomness seeded from the system
+// entropy source for choosing which entries of a cache are evicted.
+func newEvictionSource() rand.Source64 {
+	// The unpredictability of the seed is what prevents an adversary from
+	// choosing which entries are evicted, so there is no safe fallback
+	// when the system entropy source is unavailable.
+	var seed [8]byte
+	if _, err := cryptorand.Read(seed[:]); err != nil {
+		panic("unable to seed cache eviction: " + err.Error())
+	}
+	source := rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:])))
+	return source.(rand.Source64)
+}
+
+// NewSigCacheWithSource creates and initializes a new instance of SigCache
+// which chooses the entry to evict using the provided source of randomness.
+// This is primarily useful for tests and benchmarks which need the evicted
+// entries to be reproducible.
+//
+// The unpredictability of the evicted entries is what protects the cache from
+// an adversary attempting to evict specific entries, so callers must ensure the
+// source is securely seeded when the cache is used for validation.  NewSigCache
+// does this.
+func NewSigCacheWithSource(maxEntries uint, source rand.Source64) *SigCache {
+	sizeHint := cacheSizeHint(maxEntries)
 	return &SigCache{
-		validSigs:  make(map[chainhash.Hash]sigCacheEntry, maxEntries),
+		validSigs:  make(map[chainhash.Hash][]*sigCacheEntry, sizeHint),
 		maxEntries: maxEntries,
+		randSource: source,
+		now:        time.Now,
 	}
 }
 
+// NewSigCacheDeterministic creates and initializes a new instance of SigCache
+// like NewSigCache which chooses the entries to evict using a source of
+// randomness seeded with the passed seed, so that caches created with the same
+// seed evict the same entries given the same sequence of operations.  This is
+// intended for reproducible benchmarks and profiling, such as replaying
+// historical blocks.
+//
+// WARNING: Anyone who knows or guesses the seed can predict which entries are
+// evicted, which defeats the protection the randomized eviction provides
+// against an adversary evicting specific entries.  This MUST NOT be used for a
+// cache which validates transactions or blocks received from the network.
+func NewSigCacheDeterministic(maxEntries uint, seed uint64) *SigCache {
+	source := rand.NewSource(int64(seed))
+	return NewSigCacheWithSource(maxEntries, source.(rand.Source64))
+}
+
+// NewSigCacheBatch creates and initializes a new instance of SigCache like
+// NewSigCache which evicts 'evictBatch' random entries at once, rather than a
+// single one per addition, when the cache is full.  In order to do so, the cache
+// is allowed to temporarily hold up to evictBatch-1 entries more than
+// 'maxEntries' and is brought back below the max once that slack is used up.
+// This amortizes the cost of eviction during bursts of additions, such as when
+// a large block is connected.  An evictBatch of zero or one behaves exactly
+// like NewSigCache.
+func NewSigCacheBatch(maxEntries, evictBatch uint) *SigCache {
+	sigCache := NewSigCache(maxEntries)
+	sigCache.evictBatch = evictBatch
+	return sigCache
+}
+
+// NewSigCacheBytes creates and initializes a new instance of SigCache which
+// holds as many entries as fit within 'maxBytes' of memory.  Random entries are
+// evicted to make room for new entries once that number is reached.
+//
+// Since every entry holds a parsed signature and public key of roughly fixed
+// size, the memory used by each entry is estimated as the constant sum of the
+// map overhead, the entry itself, and the signature and public key it
+// references.  It is an approximation, so the actual memory used by the cache
+// may differ somewhat from the budget.
+func NewSigCacheBytes(maxBytes uint64) *SigCache {
+	return NewSigCache(uint(maxBytes / sigCacheBytesPerEntry))
+}
+
+// NewSigCacheWithInvalid creates and initializes a new instance of SigCache
+// like NewSigCache which additionally remembers up to 'maxInvalidEntries'
+// signatures which are known to be invalid.  This allows a signature which a
+// peer repeatedly includes in different transactions to be rejected without
+// verifying it again.  The valid and invalid signatures are stored separately,
+// with independent limits, so that flooding the cache with invalid signatures
+// can't evict valid ones.
+func NewSigCacheWithInvalid(maxEntries, maxInvalidEntries uint) *SigCache {
+	sigCache := NewSigCache(maxEntries)
+	sigCache.invalid = NewSigCache(maxInvalidEntries)
+	return sigCache
+}
+
+// NewSigCacheLRU creates and initializes a new instance of SigCache which
+// evicts the least recently used entry, rather than a random one, to make room
+// for new entries once 'maxEntries' is reached.  Both Add and a successful
+// Exists mark an entry as the most recently used.
+//
+// This favors signatures which were recently verified within the mempool and
+// are therefore likely to be checked again once they are included in a block.
+func NewSigCacheLRU(maxEntries uint) *SigCache {
+	sigCache := NewSigCache(maxEntries)
+	sigCache.lru = true
+	return sigCache
+}
+
+// NewSigCacheTTL creates and initializes a new instance of SigCache like
+// NewSigCache, except that entries which were added more than 'ttl' ago are
+// treated as absent.  This prevents signatures from transactions which were
+// never mined from holding on to space in the cache indefinitely.  A ttl of
+// zero means entries never expire.
+//
+// Expiry is approximate and driven by access since there is no background
+// sweep.  An expired entry is reported as absent by Exists, and the memory it
+// holds is reclaimed once a signature over the same sigHash is added or the
+// entry is chosen for eviction.
+func NewSigCacheTTL(maxEntries uint, ttl time.Duration) *SigCache {
+	sigCache := NewSigCache(maxEntries)
+	sigCache.ttl = ttl
+	return sigCache
+}
+
 // Exists returns true if an existing entry of 'sig' over 'sigHash' for public
 // key 'pubKey' is found within the SigCache. Otherwise, false is returned.
+// False is also returned when either 'sig' or 'pubKey' is nil, or when called
+// on a nil SigCache.
 //
 // NOTE: This function is safe for concurrent access. Readers won't be blocked
-// unless there exists a writer, adding an entry to the SigCache.
+// unless there exists a writer, adding an entry to the SigCache.  For caches
+// created with NewSigCacheLRU, the write lock is only taken when a hit needs
+// to be moved to the front of the recency list.
 func (s *SigCache) Exists(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) bool {
+	if s == nil || sig == nil || pubKey == nil {
+		return false
+	}
+	return s.exists(sigHash, newECDSAEntry(sig, pubKey, 0))
+}
+
+// ExistsSchnorr returns true if an existing entry of the serialized Schnorr
+// signature 'sig' over 'sigHash' for the serialized public key 'pubKey' is
+// found within the SigCache.  Otherwise, false is returned.  Schnorr entries
+// never match ECDSA entries, even when they are over the same sigHash.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) ExistsSchnorr(sigHash chainhash.Hash, sig, pubKey []byte) bool {
+	return s.exists(sigHash, newSchnorrEntry(sig, pubKey))
+}
+
+// exists returns true if an entry matching the passed one is found within the
+// SigCache.  See Exists for details.
+func (s *SigCache) exists(sigHash chainhash.Hash, want *sigCacheEntry) bool {
 	s.RLock()
-	entry, ok := s.validSigs[sigHash]
+	entry := s.lookup(sigHash, want)
+	if entry != nil && s.expired(entry) {
+		entry = nil
+	}
+	promote := entry != nil && s.lru && !entry.pinned && s.head != entry
 	s.RUnlock()
 
-	return ok && entry.pubKey.IsEqual(pubKey) && entry.sig.IsEqual(sig)
+	if entry == nil {
+		atomic.AddUint64(&s.misses, 1)
+		return false
+	}
+	atomic.AddUint64(&s.hits, 1)
+
+	if promote {
+		s.Lock()
+		// The entry may have been evicted, replaced or pinned while the
+		// lock wasn't held, in which case there is nothing to promote.
+		if s.lookup(sigHash, want) == entry && !entry.pinned {
+			s.unlinkEntry(entry)
+			s.pushFront(entry)
+		}
+		s.Unlock()
+	}
+
+	return true
 }
 
 // Add adds an entry for a signature over 'sigHash' under public key 'pubKey'
 // to the signature cache. In the event that the SigCache is 'full', an
 // existing entry is randomly chosen to be evicted in order to make space for
-// the new entry.
+// the new entry.  Caches created with NewSigCacheLRU evict the least recently
+// used entry instead.  Nothing is added when either 'sig' or 'pubKey' is nil,
+// or when called on a nil SigCache.
 //
 // NOTE: This function is safe for concurrent access. Writers will block
 // simultaneous readers until function execution has concluded.
 func (s *SigCache) Add(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) {
+	if s == nil || sig == nil || pubKey == nil {
+		return
+	}
 	s.Lock()
-	defer s.Unlock()
+	if s.maxEntries > 0 {
+		s.add(sigHash, newECDSAEntry(sig, pubKey, 0))
+	}
+	s.Unlock()
+}
+
+// AddWithFlags adds an entry for a signature over 'sigHash' under public key
+// 'pubKey' to the signature cache like Add, while providing hints about the
+// entry through the passed flags.  Entries added with SigCacheEphemeral are
+// preferred when choosing an entry to evict.  Calling AddWithFlags with no
+// flags is equivalent to calling Add.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (s *SigCache) AddWithFlags(sigHash chainhash.Hash, sig *btcec.Signature,
+	pubKey *btcec.PublicKey, flags SigCacheFlags) {
 
-	if s.maxEntries <= 0 {
+	if sig == nil || pubKey == nil {
 		return
 	}
+	s.Lock()
+	if s.maxEntries > 0 {
+		s.add(sigHash, newECDSAEntry(sig, pubKey, flags))
+	}
+	s.Unlock()
+}
+
+// AddSchnorr adds an entry for the serialized Schnorr signature 'sig' over
+// 'sigHash' under the serialized public key 'pubKey' to the signature cache,
+// evicting an existing entry when the cache is full like Add.  The passed
+// slices are copied, so the caller may reuse them.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (s *SigCache) AddSchnorr(sigHash chainhash.Hash, sig, pubKey []byte) {
+	s.Lock()
+	if s.maxEntries > 0 {
+		s.add(sigHash, newSchnorrEntry(sig, pubKey))
+	}
+	s.Unlock()
+}
+
+// newSchnorrEntry returns a new Schnorr entry for the passed serialized
+// signature and public key.
+func newSchnorrEntry(sig, pubKey []byte) *sigCacheEntry {
+	raw := make([]byte, 0, len(sig)+len(pubKey))
+	raw = append(raw, sig...)
+	raw = append(raw, pubKey...)
+	return &sigCacheEntry{raw: raw, sigLen: len(sig), sigType: sigTypeSchnorr}
+}
+
+// sigVerifyCall houses the state of a signature verification performed by
+// ExistsOrAdd so that concurrent callers interested in the same signature can
+// wait for its result instead of verifying it again.
+type sigVerifyCall struct {
+	entry *sigCacheEntry
+	wg    sync.WaitGroup
+	valid bool
+}
+
+// ExistsOrAdd returns true if an entry of 'sig' over 'sigHash' for public key
+// 'pubKey' is found within the SigCache.  Otherwise, the passed verify function
+// is invoked to verify the signature, the signature is added to the cache when
+// it is valid, and the result of the verification is returned.
+//
+// This collapses the common pattern of calling Exists, verifying the signature
+// on a miss, and then calling Add, while also ensuring a signature which is
+// concurrently requested by several callers is only verified once.  When either
+// 'sig' or 'pubKey' is nil, the verify function is invoked without consulting or
+// updating the cache.
+//
+// NOTE: This function is safe for concurrent access.  The membership check and
+// the registration of the pending verification happen under a single
+// acquisition of the write lock, however the verify function is invoked without
+// holding the lock so that the potentially slow verification doesn't block
+// other users of the cache.  Callers requesting the same signature while it is
+// being verified wait for the result.
+func (s *SigCache) ExistsOrAdd(sigHash chainhash.Hash, sig *btcec.Signature,
+	pubKey *btcec.PublicKey, verify func() bool) bool {
+
+	if sig == nil || pubKey == nil {
+		return verify()
+	}
+
+	s.Lock()
+	want := newECDSAEntry(sig, pubKey, 0)
+	entry := s.lookup(sigHash, want)
+	if entry != nil && !s.expired(entry) {
+		if s.lru && !entry.pinned && s.head != entry {
+			s.unlinkEntry(entry)
+			s.pushFront(entry)
+		}
+		s.Unlock()
+		atomic.AddUint64(&s.hits, 1)
+		return true
+	}
+	atomic.AddUint64(&s.misses, 1)
+
+	// Wait for the result when the same signature is already being
+	// verified by another caller.
+	call, ok := s.pending[sigHash]
+	if ok && call.entry.matches(want) {
+		s.Unlock()
+		call.wg.Wait()
+		return call.valid
+	}
+
+	// Register the verification so concurrent callers can wait for it
+	// unless a different signature over the same sigHash is already
+	// pending, which is not worth optimizing for.
+	if !ok {
+		call = &sigVerifyCall{entry: want}
+		call.wg.Add(1)
+		if s.pending == nil {
+			s.pending = make(map[chainhash.Hash]*sigVerifyCall)
+		}
+		s.pending[sigHash] = call
+	} else {
+		call = nil
+	}
+	s.Unlock()
+
+	// Record the result and release any waiting callers in a deferred
+	// function so they are not blocked forever, and the pending entry is
+	// not leaked, should the verify function panic.  Waiting callers treat
+	// a panicked verification as invalid.
+	var valid bool
+	defer func() {
+		s.Lock()
+		if valid {
+			s.add(sigHash, want)
+		}
+		if call != nil {
+			delete(s.pending, sigHash)
+		}
+		s.Unlock()
+
+		if call != nil {
+			call.valid = valid
+			call.wg.Done()
+		}
+	}()
+
+	valid = verify()
+	return valid
+}
+
+// add adds the passed new entry for a signature over 'sigHash' to the signature
+// cache, evicting existing entries as needed.
+//
+// This function MUST be called with the cache lock held (for writes).
+func (s *SigCache) add(sigHash chainhash.Hash, entry *sigCacheEntry) {
+	// A cache without room for any entries is disabled.
+	if s.maxEntries == 0 {
+		return
+	}
+
+	// Replace any existing entry for the same signature so it is treated
+	// as newly added.  Entries for other signatures over the same sigHash
+	// are kept.
+	if existing := s.lookup(sigHash, entry); existing != nil {
+		s.removeEntry(existing)
+	}
+
+	// Reclaim any expired entries for other signatures over the sigHash.
+	if s.ttl > 0 {
+		entries := s.validSigs[sigHash]
+		for i := len(entries) - 1; i >= 0; i-- {
+			if s.expired(entries[i]) {
+				s.removeEntry(entries[i])
+				entries = s.validSigs[sigHash]
+			}
+		}
+	}
 
 	// If adding this new entry will put us over the max number of allowed
-	// entries, then evict an entry.
-	if uint(len(s.validSigs)+1) > s.maxEntries {
-		// Remove a random entry from the map. Relying on the random
-		// starting point of Go's map iteration. It's worth noting that
-		// the random iteration starting point is not 100% guaranteed
-		// by the spec, however most Go compilers support it.
-		// Ultimately, the iteration order isn't important here because
-		// in order to manipulate which items are evicted, an adversary
-		// would need to be able to execute preimage attacks on the
-		// hashing function in order to start eviction at a specific
-		// entry.
-		for sigEntry := range s.validSigs {
-			delete(s.validSigs, sigEntry)
-			break
-		}
-	}
-	s.validSigs[sigHash] = sigCacheEntry{sig, pubKey}
+	// entries plus the eviction slack, then evict entries until there is
+	// room for it below the max, which evicts a whole batch when the slack
+	// was used up.  There is no room for the new entry when every entry is
+	// pinned.
+	if s.numEntries+1 > s.maxEntries+s.evictSlack() {
+		for s.numEntries+1 > s.maxEntries {
+			victim := s.victim()
+			if victim == nil {
+				break
+			}
+			s.removeEntry(victim)
+			atomic.AddUint64(&s.evictions, 1)
+		}
+		if s.numEntries+1 > s.maxEntries+s.evictSlack() {
+			return
+		}
+	}
+
+	entry.sigHash = sigHash
+	entry.pinned = s.pins[sigHash] > 0
+	if s.ttl > 0 {
+		entry.added = s.now()
+	}
+	s.validSigs[sigHash] = append(s.validSigs[sigHash], entry)
+	s.numEntries++
+	if !entry.pinned {
+		s.track(entry)
+	}
+}
+
+// evictSlack returns the number of entries the cache may hold beyond its max
+// before a batch of entries is evicted.
+//
+// This function MUST be called with the cache lock held (for reads).
+func (s *SigCache) evictSlack() uint {
+	if s.evictBatch <= 1 {
+		return 0
+	}
+	return s.evictBatch - 1
+}
+
+// track adds the passed entry to the recency list when it is in use and to the
+// slice of entries random victims are chosen from which holds it, if any, so
+// it may be chosen to be evicted.
+//
+// This function MUST be called with the cache lock held (for writes).
+func (s *SigCache) track(entry *sigCacheEntry) {
+	if s.lru {
+		s.pushFront(entry)
+	}
+	if victims := s.victims(entry); victims != nil {
+		entry.index = len(*victims)
+		*victims = append(*victims, entry)
+	}
+}
+
+// untrack removes the passed entry from the structures it was added to by
+// track so it is no longer chosen to be evicted.
+//
+// This function MUST be called with the cache lock held (for writes).
+func (s *SigCache) untrack(entry *sigCacheEntry) {
+	if s.lru {
+		s.unlinkEntry(entry)
+	}
+	if victims := s.victims(entry); victims != nil {
+		// Move the last entry into the slot of the removed one so the
+		// slice of entries stays dense.
+		entries := *victims
+		last := len(entries) - 1
+		entries[entry.index] = entries[last]
+		entries[entry.index].index = entry.index
+		entries[last] = nil
+		*victims = entries[:last]
+	}
+}
+
+// victims returns the slice of entries the random victims are chosen from
+// which holds the passed entry, or nil when the entry isn't in one.
+//
+// This function MUST be called with the cache lock held (for reads).
+func (s *SigCache) victims(entry *sigCacheEntry) *[]*sigCacheEntry {
+	switch {
+	case entry.flags&SigCacheEphemeral != 0:
+		return &s.ephemeral
+	case s.lru:
+		return nil
+	default:
+		return &s.entries
+	}
+}
+
+// lookup returns the entry over 'sigHash' which matches the passed one, or nil
+// when there is no such entry in the cache.  The entries under a sigHash are
+// scanned linearly since there is typically only a single one.
+//
+// This function MUST be called with the cache lock held (for reads).
+func (s *SigCache) lookup(sigHash chainhash.Hash, want *sigCacheEntry) *sigCacheEntry {
+	for _, entry := range s.validSigs[sigHash] {
+		if entry.matches(want) {
+			return entry
+		}
+	}
+	return nil
+}
+
+// expired returns whether the passed entry has outlived the ttl of the cache.
+//
+// This function MUST be called with the cache lock held (for reads).
+func (s *SigCache) expired(entry *sigCacheEntry) bool {
+	return s.ttl > 0 && s.now().Sub(entry.added) >= s.ttl
+}
+
+// victim returns the entry to evict according to the eviction policy of the
+// cache, or nil when every entry is pinned.
+//
+// This function MUST be called with the cache lock held (for writes).
+func (s *SigCache) victim() *sigCacheEntry {
+	if len(s.ephemeral) > 0 {
+		return s.randomVictim(s.ephemeral)
+	}
+	if s.lru {
+		return s.tail
+	}
+	if len(s.entries) == 0 {
+		return nil
+	}
+	return s.randomVictim(s.entries)
+}
+
+// PinAll pins the entries for the passed sigHashes, including any entries added
+// for them later, so they are never chosen to be evicted until the sigHashes are
+// unpinned with UnpinAll.  This allows block validation to pin the sigHashes of
+// the transactions in a block, which were typically already verified in the
+// mempool, so that mempool churn during validation can't evict exactly the
+// signatures about to be checked.
+//
+// Pins are counted, so each call must be matched by a call to UnpinAll with
+// the same sigHashes, and a sigHash stays pinned until every caller that pinned
+// it has unpinned it.  Pinned entries still count towards the maximum number of
+// entries, and new entries are not added while every entry is pinned.  Entries
+// may still be removed with Remove or Clear and expire as usual.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (s *SigCache) PinAll(sigHashes []chainhash.Hash) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.pins == nil {
+		s.pins = make(map[chainhash.Hash]int, len(sigHashes))
+	}
+	for _, sigHash := range sigHashes {
+		s.pins[sigHash]++
+		if s.pins[sigHash] > 1 {
+			continue
+		}
+		for _, entry := range s.validSigs[sigHash] {
+			s.untrack(entry)
+			entry.pinned = true
+		}
+	}
+}
+
+// UnpinAll releases a pin of the passed sigHashes taken with PinAll.  Once a
+// sigHash is no longer pinned, its entries may be evicted again, and entries
+// are evicted according to the eviction policy of the cache if the cache holds
+// more than the maximum number of entries, which happens when the maximum is
+// lowered while entries are pinned.  SigHashes which are not pinned are
+// ignored.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (s *SigCache) UnpinAll(sigHashes []chainhash.Hash) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, sigHash := range sigHashes {
+		pins, ok := s.pins[sigHash]
+		if !ok {
+			continue
+		}
+		if pins > 1 {
+			s.pins[sigHash] = pins - 1
+			continue
+		}
+		delete(s.pins, sigHash)
+		for _, entry := range s.validSigs[sigHash] {
+			entry.pinned = false
+			s.track(entry)
+		}
+	}
+	s.evictExcess()
+}
+
+// evictExcess evicts entries according to the eviction policy of the cache
+// until it holds no more than the maximum number of entries or every remaining
+// entry is pinned.
+//
+// This function MUST be called with the cache lock held (for writes).
+func (s *SigCache) evictExcess() {
+	for s.numEntries > s.maxEntries {
+		victim := s.victim()
+		if victim == nil {
+			return
+		}
+		s.removeEntry(victim)
+		atomic.AddUint64(&s.evictions, 1)
+	}
+}
+
+// Remove removes the entries for 'sigHash' from the signature cache, if any,
+// and returns whether any entries were removed.  This allows entries which are no
+// longer relevant, such as those introduced by transactions in a block that was
+// disconnected during a reorg, to be pruned rather than waiting for them to be
+// evicted.  Removed entries are not counted as evictions.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (s *SigCache) Remove(sigHash chainhash.Hash) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	entries, ok := s.validSigs[sigHash]
+	if !ok {
+		return false
+	}
+	for len(entries) > 0 {
+		s.removeEntry(entries[len(entries)-1])
+		entries = s.validSigs[sigHash]
+	}
+	return true
+}
+
+// Touch marks the entries for 'sigHash' as used without adding any, and returns
+// whether there are any.  Caches created with NewSigCacheLRU move the entries to
+// the front of the recency list, which extends their lifetime in the cache the
+// same way a successful existence check does.  For caches with a random
+// eviction policy it only reports whether there are any entries.  Expired
+// entries are treated as absent and touching does not count towards the hit
+// and miss statistics.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) Touch(sigHash chainhash.Hash) bool {
+	if !s.lru {
+		s.RLock()
+		defer s.RUnlock()
+		for _, entry := range s.validSigs[sigHash] {
+			if !s.expired(entry) {
+				return true
+			}
+		}
+		return false
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	found := false
+	for _, entry := range s.validSigs[sigHash] {
+		if s.expired(entry) {
+			continue
+		}
+		found = true
+		if !entry.pinned && s.head != entry {
+			s.unlinkEntry(entry)
+			s.pushFront(entry)
+		}
+	}
+	return found
+}
+
+// MarkInvalid records that 'sig' over 'sigHash' for public key 'pubKey' is
+// invalid.  It does nothing unless the cache was created with
+// NewSigCacheWithInvalid.
+//
+// Callers MUST only mark a signature as invalid after verifying it failed
+// definitively.  Since the validity of a signature only depends on the sigHash,
+// signature and public key, an entry can't cause a different, valid signature
+// to be rejected.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) MarkInvalid(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) {
+	if s.invalid != nil {
+		s.invalid.Add(sigHash, sig, pubKey)
+	}
+}
+
+// IsKnownInvalid returns true if 'sig' over 'sigHash' for public key 'pubKey'
+// was previously marked as invalid with MarkInvalid and has not been evicted
+// since.  It always returns false unless the cache was created with
+// NewSigCacheWithInvalid.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) IsKnownInvalid(sigHash chainhash.Hash, sig *btcec.Signature, pubKey *btcec.PublicKey) bool {
+	return s.invalid != nil && s.invalid.Exists(sigHash, sig, pubKey)
+}
+
+// Clear removes every entry from the signature cache, including any signatures
+// marked as invalid, and resets its usage statistics.  This is useful when all
+// cached validity assumptions must be discarded, such as during a full reindex.
+// The configured maximum number of entries and the source of randomness used
+// for eviction are kept as is.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (s *SigCache) Clear() {
+	s.Lock()
+	s.validSigs = make(map[chainhash.Hash][]*sigCacheEntry,
+		cacheSizeHint(s.maxEntries))
+	s.numEntries = 0
+	s.entries = nil
+	s.ephemeral = nil
+	s.head = nil
+	s.tail = nil
+	s.ResetStats()
+	s.Unlock()
+
+	if s.invalid != nil {
+		s.invalid.Clear()
+	}
+}
+
+// removeEntry removes the passed entry from the cache along with the structures
+// used to choose the entry to evict.
+//
+// This function MUST be called with the cache lock held (for writes).
+func (s *SigCache) removeEntry(entry *sigCacheEntry) {
+	if !entry.pinned {
+		s.untrack(entry)
+	}
+
+	entries := s.validSigs[entry.sigHash]
+	if len(entries) == 1 {
+		delete(s.validSigs, entry.sigHash)
+	} else {
+		for i, e := range entries {
+			if e == entry {
+				last := len(entries) - 1
+				entries[i] = entries[last]
+				entries[last] = nil
+				s.validSigs[entry.sigHash] = entries[:last]
+				break
+			}
+		}
+	}
+	s.numEntries--
+}
+
+// randomVictim returns a randomly chosen entry of the passed slice to evict by
+// indexing into it with a value drawn from the cache's source of randomness.
+// In order to manipulate which entries are evicted, an adversary would need to
+// be able to predict the source of randomness.
+//
+// This function MUST be called with the cache lock held (for writes) and a
+// non-empty slice.
+func (s *SigCache) randomVictim(entries []*sigCacheEntry) *sigCacheEntry {
+	return entries[randomIndex(s.randSource, len(entries))]
+}
+
+// randomIndex returns a uniformly distributed random index below n, which must
+// be positive, drawn from the passed source of randomness.
+//
+// Reducing a drawn value modulo n would slightly favor the lower indices unless
+// n divides 2^64, so values from the incomplete range at the top are rejected
+// and drawn again.  Since fewer than half of all values are rejected, this
+// takes constant expected time.
+func randomIndex(source rand.Source64, n int) int {
+	max := math.MaxUint64 - (math.MaxUint64%uint64(n)+1)%uint64(n)
+	v := source.Uint64()
+	for v > max {
+		v = source.Uint64()
+	}
+	return int(v % uint64(n))
+}
+
+// SetMaxEntries changes the maximum number of entries allowed to exist in the
+// signature cache.  When the new limit is smaller than the current number of
+// entries, entries are evicted according to the eviction policy of the cache
+// until it fits.  Pinned entries are only evicted once they are unpinned.
+// Growing the limit never evicts existing entries.  Setting it to zero empties
+// the cache, apart from any pinned entries, and causes future additions to be
+// ignored.
+//
+// This allows the size of the cache to be tuned without restarting.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (s *SigCache) SetMaxEntries(maxEntries uint) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.maxEntries = maxEntries
+	s.evictExcess()
+}
+
+// Len returns the number of entries in the signature cache.  The returned value
+// is a point-in-time snapshot which may already be stale by the time the caller
+// acts on it when the cache is concurrently modified.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) Len() int {
+	s.RLock()
+	n := int(s.numEntries)
+	s.RUnlock()
+	return n
+}
+
+// SnapshotSigHashes returns a copy of the sigHashes which currently have
+// entries in the signature cache, in no particular order.  It is intended for
+// diagnosing validation issues and deliberately doesn't expose the cached
+// signatures or public keys.  Like Len, it includes any expired entries which
+// have not been removed yet.
+//
+// The snapshot is built while holding the read lock and takes time linear in
+// the number of entries, so it must not be called in performance critical
+// paths such as transaction validation.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) SnapshotSigHashes() []chainhash.Hash {
+	s.RLock()
+	defer s.RUnlock()
+
+	sigHashes := make([]chainhash.Hash, 0, len(s.validSigs))
+	for sigHash := range s.validSigs {
+		sigHashes = append(sigHashes, sigHash)
+	}
+	return sigHashes
+}
+
+// IsFull returns whether adding an entry for a new sigHash to the signature
+// cache would cause an existing entry to be evicted.  Like Len, the result is
+// only a point-in-time snapshot.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) IsFull() bool {
+	s.RLock()
+	full := s.numEntries+1 > s.maxEntries+s.evictSlack()
+	s.RUnlock()
+	return full
+}
+
+// SigCacheStats houses usage statistics for a SigCache.
+type SigCacheStats struct {
+	// Hits is the number of calls to Exists which found a matching entry.
+	Hits uint64
+
+	// Misses is the number of calls to Exists which did not find a
+	// matching entry.
+	Misses uint64
+
+	// Evictions is the number of entries removed to make room for new
+	// entries.
+	Evictions uint64
+
+	// Entries is the number of entries in the cache.
+	Entries uint64
+}
+
+// Stats returns the usage statistics of the signature cache.  The counters are
+// read atomically without blocking writers, so they may be slightly out of
+// step with one another while the cache is in use.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) Stats() SigCacheStats {
+	return SigCacheStats{
+		Hits:      atomic.LoadUint64(&s.hits),
+		Misses:    atomic.LoadUint64(&s.misses),
+		Evictions: atomic.LoadUint64(&s.evictions),
+		Entries:   uint64(s.Len()),
+	}
+}
+
+// ResetStats resets the hit, miss, and eviction counters of the signature
+// cache to zero.  This allows callers to sample the statistics over intervals.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) ResetStats() {
+	atomic.StoreUint64(&s.hits, 0)
+	atomic.StoreUint64(&s.misses, 0)
+	atomic.StoreUint64(&s.evictions, 0)
+}
+
+// pushFront links the passed entry into the recency list as the most recently
+// used entry.
+//
+// This function MUST be called with the cache lock held (for writes).
+func (s *SigCache) pushFront(entry *sigCacheEntry) {
+	entry.prev = nil
+	entry.next = s.head
+	if s.head != nil {
+		s.head.prev = entry
+	}
+	s.head = entry
+	if s.tail == nil {
+		s.tail = entry
+	}
+}
+
+// unlinkEntry removes the passed entry from the recency list.
+//
+// This function MUST be called with the cache lock held (for writes).
+func (s *SigCache) unlinkEntry(entry *sigCacheEntry) {
+	if entry.prev != nil {
+		entry.prev.next = entry.next
+	} else {
+		s.head = entry.next
+	}
+	if entry.next != nil {
+		entry.next.prev = entry.prev
+	} else {
+		s.tail = entry.prev
+	}
+	entry.prev = nil
+	entry.next = nil
 }
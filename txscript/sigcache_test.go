@@ -6,7 +6,13 @@ package txscript
 
 import (
 	"crypto/rand"
+	"encoding/binary"
+	"math"
+	mathrand "math/rand"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/navcoin/navd/btcec"
 	"github.com/navcoin/navd/chaincfg/chainhash"
@@ -138,3 +144,1410 @@ func TestSigCacheAddMaxEntriesZeroOrNegative(t *testing.T) {
 			"been added", len(sigCache.validSigs))
 	}
 }
+
+// TestCacheSizeHint tests that the size hint the maps of caches are created with
+// matches their maximum number of entries up to the preallocation cap.
+func TestCacheSizeHint(t *testing.T) {
+	tests := []struct {
+		maxEntries uint
+		want       int
+	}{
+		{0, 0},
+		{1, 1},
+		{100000, 100000},
+		{maxCachePreallocEntries, maxCachePreallocEntries},
+		{maxCachePreallocEntries + 1, maxCachePreallocEntries},
+		{math.MaxUint32, maxCachePreallocEntries},
+	}
+	for _, test := range tests {
+		if got := cacheSizeHint(test.maxEntries); got != test.want {
+			t.Errorf("cacheSizeHint(%d): got %d, want %d",
+				test.maxEntries, got, test.want)
+		}
+	}
+}
+
+// TestSigCacheNil tests that nil signatures and public keys are never added
+// to or found in a signature cache, even when an entry with a nil signature
+// or public key was stored, instead of causing a panic.
+func TestSigCacheNil(t *testing.T) {
+	sigCache := NewSigCache(10)
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	sigCache.Add(*msg, sig, key)
+
+	nils := []struct {
+		name string
+		sig  *btcec.Signature
+		key  *btcec.PublicKey
+	}{
+		{"nil sig", nil, key},
+		{"nil pubKey", sig, nil},
+		{"nil sig and pubKey", nil, nil},
+	}
+	for _, test := range nils {
+		sigCache.Add(*msg, test.sig, test.key)
+		sigCache.AddWithFlags(*msg, test.sig, test.key, SigCacheEphemeral)
+		if sigCache.Exists(*msg, test.sig, test.key) {
+			t.Errorf("Exists (%s): entry found", test.name)
+		}
+		verified := false
+		valid := sigCache.ExistsOrAdd(*msg, test.sig, test.key,
+			func() bool {
+				verified = true
+				return true
+			})
+		if !valid || !verified {
+			t.Errorf("ExistsOrAdd (%s): signature was not verified",
+				test.name)
+		}
+	}
+	if n := len(sigCache.validSigs[*msg]); n != 1 {
+		t.Fatalf("sigcache has %d entries for sigHash, want 1", n)
+	}
+	if !sigCache.Exists(*msg, sig, key) {
+		t.Fatalf("previously added item not found in signature cache")
+	}
+
+	// Stored entries with a nil signature or public key must never match.
+	entry := newECDSAEntry(nil, nil, 0)
+	sigCache.Lock()
+	sigCache.add(*msg, entry)
+	sigCache.Unlock()
+	_, otherSig, otherKey, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	if sigCache.Exists(*msg, otherSig, otherKey) {
+		t.Fatalf("Exists: stored nil entry matched")
+	}
+}
+
+// TestSigCacheDisabled tests that a signature cache with a maximum of zero
+// entries is fully disabled, so that it never holds or reports an entry and
+// adding entries to it doesn't allocate.
+func TestSigCacheDisabled(t *testing.T) {
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	schnorrSig, schnorrKey := []byte{0x01, 0x02}, []byte{0x03}
+
+	for _, sigCache := range []*SigCache{NewSigCache(0), NewSigCacheLRU(0)} {
+		allocs := testing.AllocsPerRun(100, func() {
+			sigCache.Add(*msg, sig, key)
+			sigCache.AddWithFlags(*msg, sig, key, SigCacheEphemeral)
+			sigCache.AddSchnorr(*msg, schnorrSig, schnorrKey)
+			sigCache.Exists(*msg, sig, key)
+			sigCache.ExistsSchnorr(*msg, schnorrSig, schnorrKey)
+		})
+		if allocs != 0 {
+			t.Errorf("disabled cache allocated %v times per run", allocs)
+		}
+
+		if sigCache.Exists(*msg, sig, key) ||
+			sigCache.ExistsSchnorr(*msg, schnorrSig, schnorrKey) {
+
+			t.Fatalf("entry found in disabled cache")
+		}
+		if n := sigCache.Len(); n != 0 {
+			t.Fatalf("disabled cache holds %d entries", n)
+		}
+		if len(sigCache.validSigs) != 0 || len(sigCache.entries) != 0 {
+			t.Fatalf("disabled cache stored entries")
+		}
+	}
+}
+
+// TestSigCacheLRUEviction tests that a signature cache created with
+// NewSigCacheLRU evicts the least recently used entry, where both adding an
+// entry and a successful existence check count as a use.
+func TestSigCacheLRUEviction(t *testing.T) {
+	sigCache := NewSigCacheLRU(3)
+
+	// Generate and add three random sig triplets.
+	type sigTriplet struct {
+		msg *chainhash.Hash
+		sig *btcec.Signature
+		key *btcec.PublicKey
+	}
+	triplets := make([]sigTriplet, 4)
+	for i := range triplets {
+		msg, sig, key, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		triplets[i] = sigTriplet{msg, sig, key}
+	}
+	for _, triplet := range triplets[:3] {
+		sigCache.Add(*triplet.msg, triplet.sig, triplet.key)
+	}
+
+	// Use the first entry so that the second one becomes the least
+	// recently used.
+	first := triplets[0]
+	if !sigCache.Exists(*first.msg, first.sig, first.key) {
+		t.Fatalf("previously added item not found in signature cache")
+	}
+
+	// Adding the fourth entry must evict the second one and only the
+	// second one.
+	fourth := triplets[3]
+	sigCache.Add(*fourth.msg, fourth.sig, fourth.key)
+	if len(sigCache.validSigs) != 3 {
+		t.Fatalf("sigcache should have 3 entries, instead it has %v",
+			len(sigCache.validSigs))
+	}
+	for i, triplet := range triplets {
+		want := i != 1
+		got := sigCache.Exists(*triplet.msg, triplet.sig, triplet.key)
+		if got != want {
+			t.Errorf("entry #%d: exists = %v, want %v", i, got, want)
+		}
+	}
+
+	// Re-adding an entry under an existing sigHash must not grow the cache
+	// or break the recency list.
+	sigCache.Add(*first.msg, first.sig, first.key)
+	if len(sigCache.validSigs) != 3 {
+		t.Fatalf("sigcache should have 3 entries, instead it has %v",
+			len(sigCache.validSigs))
+	}
+	for entry := sigCache.tail; entry != nil; entry = entry.prev {
+		if sigCache.lookup(entry.sigHash, entry) != entry {
+			t.Fatalf("recency list contains entry %v which is not "+
+				"in the cache", entry.sigHash)
+		}
+	}
+}
+
+// TestSigCacheTouch tests that touching a sigHash reports whether it has
+// entries without adding any, and that it saves the entries of a signature
+// cache created with NewSigCacheLRU from being evicted next.
+func TestSigCacheTouch(t *testing.T) {
+	sigCache := NewSigCacheLRU(3)
+
+	msgs := make([]*chainhash.Hash, 4)
+	sigs := make([]*btcec.Signature, 4)
+	keys := make([]*btcec.PublicKey, 4)
+	for i := range msgs {
+		msg, sig, key, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		msgs[i], sigs[i], keys[i] = msg, sig, key
+	}
+	for i := 0; i < 3; i++ {
+		sigCache.Add(*msgs[i], sigs[i], keys[i])
+	}
+
+	// Touching a missing sigHash must not add an entry for it.
+	if sigCache.Touch(*msgs[3]) {
+		t.Fatalf("Touch: missing sigHash reported as present")
+	}
+	if len(sigCache.validSigs) != 3 {
+		t.Fatalf("Touch: sigcache has %d entries, want 3",
+			len(sigCache.validSigs))
+	}
+
+	// Touching the least recently used entry must make the second one be
+	// evicted by the next addition instead.
+	if !sigCache.Touch(*msgs[0]) {
+		t.Fatalf("Touch: cached sigHash reported as missing")
+	}
+	sigCache.Add(*msgs[3], sigs[3], keys[3])
+	for i := range msgs {
+		want := i != 1
+		got := sigCache.Exists(*msgs[i], sigs[i], keys[i])
+		if got != want {
+			t.Errorf("entry #%d: exists = %v, want %v", i, got, want)
+		}
+	}
+
+	// Caches with a random eviction policy only report membership.
+	sigCache = NewSigCache(3)
+	sigCache.Add(*msgs[0], sigs[0], keys[0])
+	if !sigCache.Touch(*msgs[0]) || sigCache.Touch(*msgs[1]) {
+		t.Fatalf("Touch: wrong membership for random eviction cache")
+	}
+	if len(sigCache.validSigs) != 1 {
+		t.Fatalf("Touch: sigcache has %d entries, want 1",
+			len(sigCache.validSigs))
+	}
+}
+
+// TestSigCacheStats tests that the signature cache correctly tracks hits,
+// misses, and evictions, and that the counters can be reset.
+func TestSigCacheStats(t *testing.T) {
+	sigCache := NewSigCache(1)
+
+	msg1, sig1, key1, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	msg2, sig2, key2, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	// One miss before adding, one hit after.
+	sigCache.Exists(*msg1, sig1, key1)
+	sigCache.Add(*msg1, sig1, key1)
+	sigCache.Exists(*msg1, sig1, key1)
+
+	// Adding a second entry to the full cache evicts the first, which
+	// results in another miss.
+	sigCache.Add(*msg2, sig2, key2)
+	sigCache.Exists(*msg1, sig1, key1)
+
+	want := SigCacheStats{Hits: 1, Misses: 2, Evictions: 1, Entries: 1}
+	if stats := sigCache.Stats(); stats != want {
+		t.Fatalf("unexpected stats - got %+v, want %+v", stats, want)
+	}
+
+	// Resetting the stats clears the counters but not the entries.
+	sigCache.ResetStats()
+	want = SigCacheStats{Entries: 1}
+	if stats := sigCache.Stats(); stats != want {
+		t.Fatalf("unexpected stats after reset - got %+v, want %+v",
+			stats, want)
+	}
+}
+
+// TestSigCacheLenIsFull tests that Len and IsFull accurately report the state
+// of the signature cache as it fills up.
+func TestSigCacheLenIsFull(t *testing.T) {
+	const sigCacheSize = 3
+	sigCache := NewSigCache(sigCacheSize)
+
+	for i := 0; i < sigCacheSize+1; i++ {
+		wantLen := i
+		if wantLen > sigCacheSize {
+			wantLen = sigCacheSize
+		}
+		if n := sigCache.Len(); n != wantLen {
+			t.Fatalf("Len #%d: got %d, want %d", i, n, wantLen)
+		}
+		wantFull := wantLen+1 > sigCacheSize
+		if full := sigCache.IsFull(); full != wantFull {
+			t.Fatalf("IsFull #%d: got %v, want %v", i, full, wantFull)
+		}
+
+		msg, sig, key, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		sigCache.Add(*msg, sig, key)
+	}
+}
+
+// fixedSource is a source of randomness which returns a fixed sequence of
+// values.  It is used to make signature cache eviction deterministic.
+type fixedSource struct {
+	values []uint64
+	next   int
+}
+
+func (s *fixedSource) Uint64() uint64 {
+	v := s.values[s.next%len(s.values)]
+	s.next++
+	return v
+}
+
+func (s *fixedSource) Int63() int64 { return int64(s.Uint64() >> 1) }
+func (s *fixedSource) Seed(int64)   {}
+
+// TestSigCacheEvictWithSource tests that a signature cache created with a
+// fixed source of randomness evicts exactly the expected entries.
+func TestSigCacheEvictWithSource(t *testing.T) {
+	// Each eviction draws a value which is used to index into the entries
+	// of the cache.  Entries start out in the order they were added and
+	// the last entry takes the place of an evicted one.
+	source := &fixedSource{values: []uint64{
+		1, // [0x10 0x20 0x30] -> evicts 0x20
+		3, // [0x10 0x30 0x40] -> 3 % 3 = 0 evicts 0x10
+	}}
+	sigCache := NewSigCacheWithSource(3, source)
+
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	sigHashes := make([]chainhash.Hash, 5)
+	for i := range sigHashes {
+		sigHashes[i][0] = byte(i+1) << 4
+	}
+	for _, sigHash := range sigHashes[:3] {
+		sigCache.Add(sigHash, sig, key)
+	}
+
+	tests := []struct {
+		add     chainhash.Hash
+		evicted chainhash.Hash
+	}{
+		{add: sigHashes[3], evicted: sigHashes[1]},
+		{add: sigHashes[4], evicted: sigHashes[0]},
+	}
+	for i, test := range tests {
+		sigCache.Add(test.add, sig, key)
+		if sigCache.Exists(test.evicted, sig, key) {
+			t.Fatalf("test #%d: entry %x was not evicted", i,
+				test.evicted[0])
+		}
+		if !sigCache.Exists(test.add, sig, key) {
+			t.Fatalf("test #%d: added entry %x not found", i,
+				test.add[0])
+		}
+		if n := sigCache.Len(); n != 3 {
+			t.Fatalf("test #%d: sigcache should have 3 entries, "+
+				"instead it has %v", i, n)
+		}
+		for j, entry := range sigCache.entries {
+			if entry.index != j {
+				t.Fatalf("test #%d: entry at %d has index %d", i,
+					j, entry.index)
+			}
+		}
+	}
+}
+
+// TestSigCacheEvictUniform tests that random victims are chosen uniformly
+// among the entries of a signature cache, including when the number of entries
+// doesn't divide the range of the source of randomness.
+func TestSigCacheEvictUniform(t *testing.T) {
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	// With 3 entries, the largest value is in the incomplete range at the
+	// top and must be rejected rather than reduced to the first entry.
+	source := &fixedSource{values: []uint64{math.MaxUint64, 4}}
+	sigCache := NewSigCacheWithSource(3, source)
+	for i := 0; i < 3; i++ {
+		sigCache.Add(chainhash.Hash{byte(i)}, sig, key)
+	}
+	victim := sigCache.victim()
+	if victim != sigCache.entries[1] {
+		t.Fatalf("victim is entry %d, want 1", victim.index)
+	}
+	if source.next != 2 {
+		t.Fatalf("victim drew %d values, want 2", source.next)
+	}
+
+	// Choose many victims among entries which don't divide 2^64 and ensure
+	// each entry is chosen close to the expected number of times.
+	const numEntries = 10
+	const draws = 100000
+	sigCache = NewSigCacheWithSource(numEntries,
+		mathrand.NewSource(1).(mathrand.Source64))
+	for i := 0; i < numEntries; i++ {
+		sigCache.Add(chainhash.Hash{byte(i)}, sig, key)
+	}
+	counts := make(map[*sigCacheEntry]int, numEntries)
+	for i := 0; i < draws; i++ {
+		counts[sigCache.victim()]++
+	}
+	want := draws / numEntries
+	for _, entry := range sigCache.entries {
+		n := counts[entry]
+		if n < want*95/100 || n > want*105/100 {
+			t.Errorf("entry %d chosen %d times, want about %d",
+				entry.index, n, want)
+		}
+	}
+}
+
+// TestSigCacheDeterministic tests that signature caches created with the same
+// seed evict the same entries given the same sequence of additions.
+func TestSigCacheDeterministic(t *testing.T) {
+	const maxEntries = 10
+	caches := []*SigCache{
+		NewSigCacheDeterministic(maxEntries, 42),
+		NewSigCacheDeterministic(maxEntries, 42),
+	}
+
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	for i := 0; i < maxEntries*10; i++ {
+		sigHash := chainhash.Hash{byte(i)}
+		for _, sigCache := range caches {
+			sigCache.Add(sigHash, sig, key)
+		}
+
+		// Both caches must hold exactly the same entries in the same
+		// order after every addition.
+		if len(caches[0].entries) != len(caches[1].entries) {
+			t.Fatalf("add #%d: caches hold %d and %d entries", i,
+				len(caches[0].entries), len(caches[1].entries))
+		}
+		for j, entry := range caches[0].entries {
+			other := caches[1].entries[j]
+			if entry.sigHash != other.sigHash {
+				t.Fatalf("add #%d: entry %d is %x and %x", i, j,
+					entry.sigHash[0], other.sigHash[0])
+			}
+		}
+	}
+}
+
+// TestSigCacheRemove tests that entries can be removed from both random and
+// LRU signature caches without being counted as evictions.
+func TestSigCacheRemove(t *testing.T) {
+	for _, newCache := range []func(uint) *SigCache{NewSigCache, NewSigCacheLRU} {
+		sigCache := newCache(10)
+
+		msg1, sig1, key1, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		msg2, sig2, key2, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		sigCache.Add(*msg1, sig1, key1)
+		sigCache.Add(*msg2, sig2, key2)
+
+		if !sigCache.Remove(*msg1) {
+			t.Fatalf("Remove: existing entry was not removed")
+		}
+		if sigCache.Remove(*msg1) {
+			t.Fatalf("Remove: entry removed twice")
+		}
+		if sigCache.Exists(*msg1, sig1, key1) {
+			t.Fatalf("removed entry found in signature cache")
+		}
+		if !sigCache.Exists(*msg2, sig2, key2) {
+			t.Fatalf("remaining entry not found in signature cache")
+		}
+		if stats := sigCache.Stats(); stats.Evictions != 0 ||
+			stats.Entries != 1 {
+
+			t.Fatalf("unexpected stats after remove: %+v", stats)
+		}
+	}
+}
+
+// TestSigCacheBytes tests that a signature cache created with a byte budget
+// holds as many entries as the estimated per-entry size allows.
+func TestSigCacheBytes(t *testing.T) {
+	tests := []struct {
+		maxBytes   uint64
+		maxEntries uint
+	}{
+		{maxBytes: 0, maxEntries: 0},
+		{maxBytes: sigCacheBytesPerEntry - 1, maxEntries: 0},
+		{maxBytes: sigCacheBytesPerEntry, maxEntries: 1},
+		{maxBytes: sigCacheBytesPerEntry*2 + sigCacheBytesPerEntry/2, maxEntries: 2},
+		{maxBytes: 32 << 20, maxEntries: (32 << 20) / sigCacheBytesPerEntry},
+	}
+
+	for i, test := range tests {
+		sigCache := NewSigCacheBytes(test.maxBytes)
+		if sigCache.maxEntries != test.maxEntries {
+			t.Errorf("test #%d: got %d max entries, want %d", i,
+				sigCache.maxEntries, test.maxEntries)
+		}
+	}
+}
+
+// TestSigCacheExistsOrAdd tests that ExistsOrAdd only verifies signatures
+// which are not already cached, only caches valid signatures, and verifies a
+// signature concurrently requested by several callers only once.
+func TestSigCacheExistsOrAdd(t *testing.T) {
+	sigCache := NewSigCache(10)
+
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	// An invalid signature must not be cached.
+	var calls int32
+	invalid := func() bool {
+		atomic.AddInt32(&calls, 1)
+		return false
+	}
+	if sigCache.ExistsOrAdd(*msg, sig, key, invalid) {
+		t.Fatalf("ExistsOrAdd: invalid signature reported as valid")
+	}
+	if sigCache.Exists(*msg, sig, key) {
+		t.Fatalf("invalid signature found in signature cache")
+	}
+
+	// Concurrent requests for the same valid signature only verify it
+	// once.  The verify function blocks until the remaining callers have
+	// been started so the requests are likely to overlap.  Callers which
+	// arrive while it is blocked wait for its result and callers which
+	// arrive after it completes find the cached entry, so the signature
+	// is verified exactly once regardless of scheduling.
+	atomic.StoreInt32(&calls, 0)
+	const numCallers = 8
+	started := make(chan struct{})
+	release := make(chan struct{})
+	valid := func() bool {
+		atomic.AddInt32(&calls, 1)
+		close(started)
+		<-release
+		return true
+	}
+	var wg sync.WaitGroup
+	wg.Add(numCallers)
+	results := make(chan bool, numCallers)
+	for i := 0; i < numCallers; i++ {
+		go func() {
+			defer wg.Done()
+			results <- sigCache.ExistsOrAdd(*msg, sig, key, valid)
+		}()
+		if i == 0 {
+			<-started
+		}
+	}
+	close(release)
+	wg.Wait()
+	close(results)
+	for result := range results {
+		if !result {
+			t.Fatalf("ExistsOrAdd: valid signature reported as invalid")
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("signature verified %d times, want 1", n)
+	}
+
+	// The signature is now cached, so it must not be verified again.
+	if !sigCache.ExistsOrAdd(*msg, sig, key, invalid) {
+		t.Fatalf("ExistsOrAdd: cached signature reported as invalid")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("signature verified %d times, want 1", n)
+	}
+	if len(sigCache.pending) != 0 {
+		t.Fatalf("%d pending verifications remain", len(sigCache.pending))
+	}
+}
+
+// TestSigCacheExistsOrAddPanic tests that a verify function which panics
+// doesn't leave a pending verification behind which would block later callers
+// for the same signature.
+func TestSigCacheExistsOrAddPanic(t *testing.T) {
+	sigCache := NewSigCache(10)
+
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("ExistsOrAdd: verify panic was not propagated")
+			}
+		}()
+		sigCache.ExistsOrAdd(*msg, sig, key, func() bool {
+			panic("verify failure")
+		})
+	}()
+
+	if len(sigCache.pending) != 0 {
+		t.Fatalf("%d pending verifications remain", len(sigCache.pending))
+	}
+	if sigCache.Exists(*msg, sig, key) {
+		t.Fatalf("signature with panicked verification was cached")
+	}
+	if !sigCache.ExistsOrAdd(*msg, sig, key, func() bool { return true }) {
+		t.Fatalf("ExistsOrAdd: valid signature reported as invalid")
+	}
+}
+
+// TestSigCacheSetMaxEntries tests that shrinking the maximum number of entries
+// of a full signature cache evicts entries down to the new limit, that growing
+// it keeps the existing entries, and that a limit of zero empties the cache.
+func TestSigCacheSetMaxEntries(t *testing.T) {
+	for _, newCache := range []func(uint) *SigCache{NewSigCache, NewSigCacheLRU} {
+		const sigCacheSize = 5
+		sigCache := newCache(sigCacheSize)
+		for i := 0; i < sigCacheSize; i++ {
+			msg, sig, key, err := genRandomSig()
+			if err != nil {
+				t.Fatalf("unable to generate random signature test data")
+			}
+			sigCache.Add(*msg, sig, key)
+		}
+		if !sigCache.IsFull() {
+			t.Fatalf("signature cache should be full")
+		}
+
+		sigCache.SetMaxEntries(2)
+		if n := sigCache.Len(); n != 2 {
+			t.Fatalf("Len after shrink: got %d, want 2", n)
+		}
+		if stats := sigCache.Stats(); stats.Evictions != sigCacheSize-2 {
+			t.Fatalf("Evictions after shrink: got %d, want %d",
+				stats.Evictions, sigCacheSize-2)
+		}
+
+		sigCache.SetMaxEntries(10)
+		if n := sigCache.Len(); n != 2 {
+			t.Fatalf("Len after grow: got %d, want 2", n)
+		}
+		if sigCache.IsFull() {
+			t.Fatalf("signature cache should not be full after grow")
+		}
+
+		sigCache.SetMaxEntries(0)
+		if n := sigCache.Len(); n != 0 {
+			t.Fatalf("Len after zero: got %d, want 0", n)
+		}
+		msg, sig, key, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		sigCache.Add(*msg, sig, key)
+		if sigCache.Exists(*msg, sig, key) {
+			t.Fatalf("entry added to signature cache with zero max entries")
+		}
+	}
+}
+
+// TestSigCacheSameSigHash tests that signatures by different keys over the same
+// sigHash, such as those checked by multisig scripts, don't overwrite one
+// another and are counted as individual entries.
+func TestSigCacheSameSigHash(t *testing.T) {
+	for _, newCache := range []func(uint) *SigCache{NewSigCache, NewSigCacheLRU} {
+		sigCache := newCache(3)
+
+		msg, sig1, key1, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		_, sig2, key2, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		sigCache.Add(*msg, sig1, key1)
+		sigCache.Add(*msg, sig2, key2)
+
+		if !sigCache.Exists(*msg, sig1, key1) {
+			t.Fatalf("first signature over sigHash not found")
+		}
+		if !sigCache.Exists(*msg, sig2, key2) {
+			t.Fatalf("second signature over sigHash not found")
+		}
+		if sigCache.Exists(*msg, sig1, key2) {
+			t.Fatalf("mismatched signature and key found")
+		}
+
+		// Adding a signature which already exists must not add a
+		// duplicate entry.
+		sigCache.Add(*msg, sig1, key1)
+		if n := sigCache.Len(); n != 2 {
+			t.Fatalf("Len: got %d, want 2", n)
+		}
+
+		// Filling the cache must evict individual entries rather than
+		// all of the entries under a sigHash.
+		for i := 0; i < 2; i++ {
+			otherMsg, sig, key, err := genRandomSig()
+			if err != nil {
+				t.Fatalf("unable to generate random signature " +
+					"test data")
+			}
+			sigCache.Add(*otherMsg, sig, key)
+		}
+		if n := sigCache.Len(); n != 3 {
+			t.Fatalf("Len after eviction: got %d, want 3", n)
+		}
+		if stats := sigCache.Stats(); stats.Evictions != 1 {
+			t.Fatalf("Evictions: got %d, want 1", stats.Evictions)
+		}
+
+		// Removing the sigHash must remove every remaining entry under
+		// it.
+		wantLen := 3 - len(sigCache.validSigs[*msg])
+		sigCache.Remove(*msg)
+		if sigCache.Exists(*msg, sig1, key1) ||
+			sigCache.Exists(*msg, sig2, key2) {
+
+			t.Fatalf("entry found after removing sigHash")
+		}
+		if n := sigCache.Len(); n != wantLen {
+			t.Fatalf("Len after remove: got %d, want %d", n, wantLen)
+		}
+	}
+}
+
+// TestSigCacheTTL tests that entries of a signature cache created with
+// NewSigCacheTTL are treated as absent once they expire, and that expired
+// entries are reclaimed when a signature over the same sigHash is added.
+func TestSigCacheTTL(t *testing.T) {
+	const ttl = time.Minute
+	sigCache := NewSigCacheTTL(10, ttl)
+	now := time.Unix(1500000000, 0)
+	sigCache.now = func() time.Time { return now }
+
+	msg, sig1, key1, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	_, sig2, key2, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	sigCache.Add(*msg, sig1, key1)
+
+	now = now.Add(ttl - time.Second)
+	if !sigCache.Exists(*msg, sig1, key1) {
+		t.Fatalf("entry expired before its ttl")
+	}
+
+	now = now.Add(time.Second)
+	if sigCache.Exists(*msg, sig1, key1) {
+		t.Fatalf("expired entry found in signature cache")
+	}
+	if sigCache.ExistsOrAdd(*msg, sig1, key1, func() bool { return false }) {
+		t.Fatalf("ExistsOrAdd: expired entry reported as valid")
+	}
+
+	// Adding another signature over the same sigHash reclaims the expired
+	// entry.
+	sigCache.Add(*msg, sig2, key2)
+	if n := sigCache.Len(); n != 1 {
+		t.Fatalf("Len: got %d, want 1", n)
+	}
+	if !sigCache.Exists(*msg, sig2, key2) {
+		t.Fatalf("newly added entry not found in signature cache")
+	}
+
+	// A ttl of zero never expires entries.
+	sigCache = NewSigCacheTTL(10, 0)
+	sigCache.now = func() time.Time { return now }
+	sigCache.Add(*msg, sig1, key1)
+	now = now.Add(24 * time.Hour)
+	if !sigCache.Exists(*msg, sig1, key1) {
+		t.Fatalf("entry expired with a zero ttl")
+	}
+}
+
+// TestSigCacheClear tests that clearing a signature cache removes every entry
+// and resets its statistics while leaving it usable.
+func TestSigCacheClear(t *testing.T) {
+	for _, newCache := range []func(uint) *SigCache{NewSigCache, NewSigCacheLRU} {
+		sigCache := newCache(2)
+
+		var msgs []*chainhash.Hash
+		var sigs []*btcec.Signature
+		var keys []*btcec.PublicKey
+		for i := 0; i < 3; i++ {
+			msg, sig, key, err := genRandomSig()
+			if err != nil {
+				t.Fatalf("unable to generate random signature " +
+					"test data")
+			}
+			sigCache.Add(*msg, sig, key)
+			sigCache.Exists(*msg, sig, key)
+			msgs = append(msgs, msg)
+			sigs = append(sigs, sig)
+			keys = append(keys, key)
+		}
+		randSource := sigCache.randSource
+
+		sigCache.Clear()
+		if n := sigCache.Len(); n != 0 {
+			t.Fatalf("Len after clear: got %d, want 0", n)
+		}
+		if stats := sigCache.Stats(); stats != (SigCacheStats{}) {
+			t.Fatalf("unexpected stats after clear: %+v", stats)
+		}
+		if sigCache.randSource != randSource {
+			t.Fatalf("source of randomness replaced by clear")
+		}
+		for i := range msgs {
+			if sigCache.Exists(*msgs[i], sigs[i], keys[i]) {
+				t.Fatalf("entry #%d found after clear", i)
+			}
+		}
+
+		// The cache must remain usable, including eviction.
+		for i := range msgs {
+			sigCache.Add(*msgs[i], sigs[i], keys[i])
+		}
+		if n := sigCache.Len(); n != 2 {
+			t.Fatalf("Len after refill: got %d, want 2", n)
+		}
+	}
+}
+
+// TestSigCacheSchnorr tests that Schnorr entries can be added and found, and
+// that they never match ECDSA entries or Schnorr entries for other signatures
+// over the same sigHash.
+func TestSigCacheSchnorr(t *testing.T) {
+	sigCache := NewSigCache(10)
+
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	schnorrSig := make([]byte, 64)
+	schnorrSig[0] = 0x01
+	schnorrKey := key.SerializeCompressed()[1:]
+
+	sigCache.AddSchnorr(*msg, schnorrSig, schnorrKey)
+	if !sigCache.ExistsSchnorr(*msg, schnorrSig, schnorrKey) {
+		t.Fatalf("Schnorr entry not found in signature cache")
+	}
+	if sigCache.Exists(*msg, sig, key) {
+		t.Fatalf("ECDSA signature matched Schnorr entry")
+	}
+
+	// Modifying the passed slices must not affect the cached entry.
+	schnorrSig[0] = 0x02
+	if sigCache.ExistsSchnorr(*msg, schnorrSig, schnorrKey) {
+		t.Fatalf("unrelated Schnorr signature found in signature cache")
+	}
+	schnorrSig[0] = 0x01
+
+	// Moving bytes between the signature and public key must not match.
+	shifted := append(append([]byte(nil), schnorrSig...), schnorrKey[0])
+	if sigCache.ExistsSchnorr(*msg, shifted, schnorrKey[1:]) {
+		t.Fatalf("Schnorr entry matched with a different split")
+	}
+
+	sigCache.Add(*msg, sig, key)
+	if !sigCache.Exists(*msg, sig, key) {
+		t.Fatalf("ECDSA entry not found in signature cache")
+	}
+	if !sigCache.ExistsSchnorr(*msg, schnorrSig, schnorrKey) {
+		t.Fatalf("Schnorr entry overwritten by ECDSA entry")
+	}
+	if n := sigCache.Len(); n != 2 {
+		t.Fatalf("Len: got %d, want 2", n)
+	}
+}
+
+// TestSigCacheEphemeral tests that entries added with SigCacheEphemeral are
+// evicted before any other entries.
+func TestSigCacheEphemeral(t *testing.T) {
+	for _, newCache := range []func(uint) *SigCache{NewSigCache, NewSigCacheLRU} {
+		sigCache := newCache(3)
+
+		type sigEntry struct {
+			msg *chainhash.Hash
+			sig *btcec.Signature
+			key *btcec.PublicKey
+		}
+		entries := make([]sigEntry, 5)
+		for i := range entries {
+			msg, sig, key, err := genRandomSig()
+			if err != nil {
+				t.Fatalf("unable to generate random signature " +
+					"test data")
+			}
+			entries[i] = sigEntry{msg, sig, key}
+		}
+
+		// Add an ephemeral entry between two regular ones and ensure
+		// it is the one evicted once the cache is full.
+		sigCache.AddWithFlags(*entries[0].msg, entries[0].sig,
+			entries[0].key, 0)
+		sigCache.AddWithFlags(*entries[1].msg, entries[1].sig,
+			entries[1].key, SigCacheEphemeral)
+		sigCache.Add(*entries[2].msg, entries[2].sig, entries[2].key)
+		sigCache.Add(*entries[3].msg, entries[3].sig, entries[3].key)
+		if sigCache.Exists(*entries[1].msg, entries[1].sig, entries[1].key) {
+			t.Fatalf("ephemeral entry was not evicted")
+		}
+		for _, i := range []int{0, 2, 3} {
+			if !sigCache.Exists(*entries[i].msg, entries[i].sig,
+				entries[i].key) {
+
+				t.Fatalf("regular entry #%d was evicted", i)
+			}
+		}
+		if len(sigCache.ephemeral) != 0 {
+			t.Fatalf("%d ephemeral entries remain",
+				len(sigCache.ephemeral))
+		}
+
+		// Removing an ephemeral entry must remove it from the slice of
+		// ephemeral entries.
+		sigCache.Remove(*entries[0].msg)
+		sigCache.AddWithFlags(*entries[4].msg, entries[4].sig,
+			entries[4].key, SigCacheEphemeral)
+		sigCache.Remove(*entries[4].msg)
+		if len(sigCache.ephemeral) != 0 {
+			t.Fatalf("%d ephemeral entries remain after remove",
+				len(sigCache.ephemeral))
+		}
+		if n := sigCache.Len(); n != 2 {
+			t.Fatalf("Len: got %d, want 2", n)
+		}
+	}
+}
+
+// TestSigCacheInvalid tests that signatures marked as invalid are remembered
+// separately from valid signatures and that each store respects its own limit.
+func TestSigCacheInvalid(t *testing.T) {
+	const maxEntries, maxInvalidEntries = 3, 2
+	sigCache := NewSigCacheWithInvalid(maxEntries, maxInvalidEntries)
+
+	type sigEntry struct {
+		msg *chainhash.Hash
+		sig *btcec.Signature
+		key *btcec.PublicKey
+	}
+	genEntries := func(n int) []sigEntry {
+		entries := make([]sigEntry, n)
+		for i := range entries {
+			msg, sig, key, err := genRandomSig()
+			if err != nil {
+				t.Fatalf("unable to generate random signature " +
+					"test data")
+			}
+			entries[i] = sigEntry{msg, sig, key}
+		}
+		return entries
+	}
+
+	valid := genEntries(maxEntries)
+	for _, e := range valid {
+		sigCache.Add(*e.msg, e.sig, e.key)
+	}
+
+	// Flood the invalid store with more signatures than it can hold.
+	invalid := genEntries(maxInvalidEntries * 3)
+	for _, e := range invalid {
+		sigCache.MarkInvalid(*e.msg, e.sig, e.key)
+		if !sigCache.IsKnownInvalid(*e.msg, e.sig, e.key) {
+			t.Fatalf("signature marked invalid is not known invalid")
+		}
+		if sigCache.Exists(*e.msg, e.sig, e.key) {
+			t.Fatalf("invalid signature found in signature cache")
+		}
+	}
+	if n := sigCache.invalid.Len(); n != maxInvalidEntries {
+		t.Fatalf("invalid store holds %d entries, want %d", n,
+			maxInvalidEntries)
+	}
+
+	// None of the valid signatures may have been evicted or be reported
+	// as invalid.
+	for i, e := range valid {
+		if !sigCache.Exists(*e.msg, e.sig, e.key) {
+			t.Fatalf("valid entry #%d evicted by invalid entries", i)
+		}
+		if sigCache.IsKnownInvalid(*e.msg, e.sig, e.key) {
+			t.Fatalf("valid entry #%d reported as invalid", i)
+		}
+	}
+	if n := sigCache.Len(); n != maxEntries {
+		t.Fatalf("Len: got %d, want %d", n, maxEntries)
+	}
+
+	// Clearing the cache forgets the invalid signatures too.
+	sigCache.Clear()
+	if n := sigCache.invalid.Len(); n != 0 {
+		t.Fatalf("invalid store holds %d entries after clear", n)
+	}
+
+	// Caches without an invalid store never report signatures as invalid.
+	sigCache = NewSigCache(maxEntries)
+	e := invalid[0]
+	sigCache.MarkInvalid(*e.msg, e.sig, e.key)
+	if sigCache.IsKnownInvalid(*e.msg, e.sig, e.key) {
+		t.Fatalf("signature known invalid without an invalid store")
+	}
+}
+
+// TestNopSigCache tests that the no-op signature cache never reports an entry
+// as existing, even directly after it has been added.
+func TestNopSigCache(t *testing.T) {
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	var sigCache SignatureCache = NopSigCache{}
+	sigCache.Add(*msg, sig, key)
+	if sigCache.Exists(*msg, sig, key) {
+		t.Fatalf("entry found in the no-op sig cache")
+	}
+}
+
+// TestNilSigCachePointer tests that nil signature cache pointers stored in the
+// SignatureCache interface disable caching rather than causing a panic, both
+// when used directly and when passed to the script engine.
+func TestNilSigCachePointer(t *testing.T) {
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	var nilSigCache *SigCache
+	nilSigCache.Add(*msg, sig, key)
+	if nilSigCache.Exists(*msg, sig, key) {
+		t.Fatalf("entry found in nil sig cache")
+	}
+
+	const flags = ScriptBip16 | ScriptVerifyDERSignatures |
+		ScriptVerifyStrictEncoding
+	txns, prevOuts := genVerifyTxns(t, 2)
+	var nilSharded *ShardedSigCache
+	for _, sigCache := range []SignatureCache{nilSigCache, nilSharded} {
+		if normalizeSigCache(sigCache) != (NopSigCache{}) {
+			t.Errorf("normalizeSigCache: %T nil was not replaced",
+				sigCache)
+		}
+		err := VerifyBlockScripts(txns, prevOuts, flags, sigCache, nil,
+			nil, 1)
+		if err != nil {
+			t.Errorf("VerifyBlockScripts (%T nil): unexpected error: %v",
+				sigCache, err)
+		}
+	}
+}
+
+// TestSigCachePin tests that pinned entries survive any number of additions
+// which would otherwise evict them until they are unpinned.
+func TestSigCachePin(t *testing.T) {
+	const maxEntries = 10
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	pinned := make([]chainhash.Hash, 2)
+	for i := range pinned {
+		if _, err := rand.Read(pinned[i][:]); err != nil {
+			t.Fatalf("unable to generate sigHash: %v", err)
+		}
+	}
+
+	// addFillers adds entries for the passed number of random sigHashes.
+	addFillers := func(sigCache *SigCache, n int) {
+		var sigHash chainhash.Hash
+		for i := 0; i < n; i++ {
+			if _, err := rand.Read(sigHash[:]); err != nil {
+				t.Fatalf("unable to generate sigHash: %v", err)
+			}
+			sigCache.Add(sigHash, sig, key)
+		}
+	}
+
+	for _, newCache := range []func(uint) *SigCache{NewSigCache, NewSigCacheLRU} {
+		sigCache := newCache(maxEntries)
+
+		// Pin the first sigHash before its entry is added and the
+		// second after.  The second is pinned twice.
+		sigCache.PinAll(pinned[:1])
+		sigCache.Add(pinned[0], sig, key)
+		sigCache.Add(pinned[1], sig, key)
+		sigCache.AddWithFlags(*msg, sig, key, SigCacheEphemeral)
+		sigCache.PinAll(pinned[1:])
+		sigCache.PinAll(pinned[1:])
+
+		addFillers(sigCache, maxEntries*20)
+		for i := range pinned {
+			if !sigCache.Exists(pinned[i], sig, key) {
+				t.Fatalf("pinned entry #%d was evicted", i)
+			}
+		}
+		if sigCache.Exists(*msg, sig, key) {
+			t.Fatalf("unpinned ephemeral entry was not evicted")
+		}
+		if n := sigCache.Len(); n != maxEntries {
+			t.Fatalf("Len: got %d, want %d", n, maxEntries)
+		}
+
+		// Unpinning once must leave the second sigHash pinned while
+		// unpinning the first makes its entry evictable again.
+		sigCache.UnpinAll(pinned)
+		addFillers(sigCache, maxEntries*20)
+		if !sigCache.Exists(pinned[1], sig, key) {
+			t.Fatalf("entry pinned twice was evicted after a " +
+				"single unpin")
+		}
+		want := newECDSAEntry(sig, key, 0)
+		if entry := sigCache.lookup(pinned[0], want); entry != nil &&
+			entry.pinned {
+
+			t.Fatalf("unpinned entry is still pinned")
+		}
+
+		// Shrinking the cache must keep the pinned entry until it is
+		// unpinned.
+		sigCache.SetMaxEntries(0)
+		if n := sigCache.Len(); n != 1 {
+			t.Fatalf("Len: got %d, want 1", n)
+		}
+		sigCache.UnpinAll(pinned[1:])
+		if n := sigCache.Len(); n != 0 {
+			t.Fatalf("Len: got %d, want 0", n)
+		}
+
+		// New entries must not be added while every entry is pinned.
+		sigCache = newCache(uint(len(pinned)))
+		sigCache.PinAll(pinned)
+		for i := range pinned {
+			sigCache.Add(pinned[i], sig, key)
+		}
+		sigCache.Add(*msg, sig, key)
+		if sigCache.Exists(*msg, sig, key) {
+			t.Fatalf("entry added to a cache of pinned entries")
+		}
+		if n := sigCache.Len(); n != len(pinned) {
+			t.Fatalf("Len: got %d, want %d", n, len(pinned))
+		}
+	}
+}
+
+// TestSigCacheSnapshotSigHashes tests that a snapshot of the sigHashes in a
+// signature cache reflects additions and removals, holds every sigHash once and
+// is a copy.
+func TestSigCacheSnapshotSigHashes(t *testing.T) {
+	sigCache := NewSigCache(10)
+	if n := len(sigCache.SnapshotSigHashes()); n != 0 {
+		t.Fatalf("snapshot of empty cache holds %d sigHashes", n)
+	}
+
+	// Add two signatures over the same sigHash and one over another.
+	msg1, sig1, key1, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	_, sig2, key2, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	msg3, sig3, key3, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	sigCache.Add(*msg1, sig1, key1)
+	sigCache.Add(*msg1, sig2, key2)
+	sigCache.Add(*msg3, sig3, key3)
+
+	snapshot := sigCache.SnapshotSigHashes()
+	want := map[chainhash.Hash]bool{*msg1: true, *msg3: true}
+	if len(snapshot) != len(want) {
+		t.Fatalf("snapshot holds %d sigHashes, want %d", len(snapshot),
+			len(want))
+	}
+	for _, sigHash := range snapshot {
+		if !want[sigHash] {
+			t.Fatalf("snapshot holds unexpected sigHash %v", sigHash)
+		}
+	}
+
+	// Modifying the snapshot must not affect the cache.
+	snapshot[0] = chainhash.Hash{}
+	if !sigCache.Exists(*msg1, sig1, key1) ||
+		!sigCache.Exists(*msg3, sig3, key3) {
+
+		t.Fatalf("modifying snapshot changed the cache")
+	}
+
+	sigCache.Remove(*msg1)
+	snapshot = sigCache.SnapshotSigHashes()
+	if len(snapshot) != 1 || snapshot[0] != *msg3 {
+		t.Fatalf("snapshot after removal is %v, want [%v]", snapshot,
+			*msg3)
+	}
+}
+
+// TestSigCacheBatch tests that a signature cache created with NewSigCacheBatch
+// evicts whole batches of entries and only exceeds its maximum number of
+// entries by less than the batch size.
+func TestSigCacheBatch(t *testing.T) {
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	tests := []struct {
+		evictBatch uint
+		maxLen     int
+	}{
+		{evictBatch: 0, maxLen: 10},
+		{evictBatch: 1, maxLen: 10},
+		{evictBatch: 4, maxLen: 13},
+		{evictBatch: 10, maxLen: 19},
+	}
+	for _, test := range tests {
+		const maxEntries = 10
+		sigCache := NewSigCacheBatch(maxEntries, test.evictBatch)
+		batch := int(test.evictBatch)
+		if batch == 0 {
+			batch = 1
+		}
+
+		var passes int
+		prevLen := 0
+		for i := 0; i < 200; i++ {
+			sigHash := chainhash.Hash{byte(i), byte(i >> 8)}
+			sigCache.Add(sigHash, sig, key)
+			if !sigCache.Exists(sigHash, sig, key) {
+				t.Fatalf("batch %d: added entry #%d not found",
+					test.evictBatch, i)
+			}
+
+			n := sigCache.Len()
+			if n > test.maxLen {
+				t.Fatalf("batch %d: cache holds %d entries, want at "+
+					"most %d", test.evictBatch, n, test.maxLen)
+			}
+
+			// Every eviction must remove a whole batch, leaving the
+			// cache at its max after adding the new entry.
+			if n <= prevLen {
+				if n != maxEntries || prevLen-n+1 != batch {
+					t.Fatalf("batch %d: cache went from %d to %d "+
+						"entries", test.evictBatch, prevLen, n)
+				}
+				passes++
+			}
+			prevLen = n
+		}
+
+		evictions := sigCache.Stats().Evictions
+		if evictions != uint64(passes*batch) {
+			t.Fatalf("batch %d: got %d evictions in %d passes, want %d",
+				test.evictBatch, evictions, passes, passes*batch)
+		}
+		if sigCache.IsFull() != (prevLen == test.maxLen) {
+			t.Fatalf("batch %d: IsFull is %v with %d entries",
+				test.evictBatch, sigCache.IsFull(), prevLen)
+		}
+	}
+}
+
+// TestSigCacheKeyTag tests that the key tag of ECDSA entries only lets entries
+// for different public keys fail to match early and never changes whether two
+// entries match.
+func TestSigCacheKeyTag(t *testing.T) {
+	msg, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	// A separately parsed copy of the key must have the same tag and
+	// match.
+	keyCopy, err := btcec.ParsePubKey(key.SerializeCompressed(), btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to parse public key: %v", err)
+	}
+	entry := newECDSAEntry(sig, key, 0)
+	if !entry.matches(newECDSAEntry(sig, keyCopy, 0)) {
+		t.Fatalf("entries for the same key don't match")
+	}
+
+	// An entry for another key must not match, even when the tags happen
+	// to be equal.
+	_, _, otherKey, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+	other := newECDSAEntry(sig, otherKey, 0)
+	if entry.matches(other) {
+		t.Fatalf("entries for different keys match")
+	}
+	other.keyTag = entry.keyTag
+	if entry.matches(other) {
+		t.Fatalf("entries for different keys with equal tags match")
+	}
+
+	sigCache := NewSigCache(10)
+	sigCache.Add(*msg, sig, key)
+	if !sigCache.Exists(*msg, sig, keyCopy) {
+		t.Fatalf("entry not found with a copy of its key")
+	}
+	if sigCache.Exists(*msg, sig, otherKey) {
+		t.Fatalf("entry found with a different key")
+	}
+}
+
+// TestSigCacheConcurrentStress tests that signature caches at capacity behave
+// correctly under concurrent additions, existence checks and evictions.  The
+// number of entries must never exceed the max and no signature which was never
+// added may be reported as present.  It is most useful when run with -race.
+func TestSigCacheConcurrentStress(t *testing.T) {
+	_, sig, key, err := genRandomSig()
+	if err != nil {
+		t.Fatalf("unable to generate random signature test data")
+	}
+
+	const (
+		maxEntries   = 100
+		numWorkers   = 16
+		opsPerWorker = 2000
+	)
+	tests := []struct {
+		name     string
+		sigCache *SigCache
+		maxLen   int
+	}{
+		{"random", NewSigCache(maxEntries), maxEntries},
+		{"lru", NewSigCacheLRU(maxEntries), maxEntries},
+		{"batch", NewSigCacheBatch(maxEntries, 8), maxEntries + 7},
+	}
+
+	for _, test := range tests {
+		sigCache := test.sigCache
+
+		// sigHashFor returns the sigHash the passed worker uses for its
+		// passed operation.  The added flag is only set for sigHashes
+		// which are added, so that the others are never in the cache.
+		sigHashFor := func(worker, op int, added bool) chainhash.Hash {
+			var sigHash chainhash.Hash
+			sigHash[0] = byte(worker)
+			binary.LittleEndian.PutUint32(sigHash[1:], uint32(op))
+			if added {
+				sigHash[5] = 1
+			}
+			return sigHash
+		}
+
+		var wg sync.WaitGroup
+		wg.Add(numWorkers)
+		for worker := 0; worker < numWorkers; worker++ {
+			go func(worker int) {
+				defer wg.Done()
+				for op := 0; op < opsPerWorker; op++ {
+					sigHash := sigHashFor(worker, op, true)
+					sigCache.Add(sigHash, sig, key)
+
+					// Check a recent addition, which may
+					// have been evicted already, and one
+					// which was never added.
+					recent := sigHashFor(worker, op/2, true)
+					sigCache.Exists(recent, sig, key)
+					missing := sigHashFor(worker, op, false)
+					if sigCache.Exists(missing, sig, key) {
+						t.Errorf("%s: signature which was "+
+							"never added found", test.name)
+						return
+					}
+
+					if n := sigCache.Len(); n > test.maxLen {
+						t.Errorf("%s: cache holds %d entries, "+
+							"want at most %d", test.name, n,
+							test.maxLen)
+						return
+					}
+				}
+			}(worker)
+		}
+		wg.Wait()
+
+		if n := sigCache.Len(); n > test.maxLen || n == 0 {
+			t.Fatalf("%s: cache holds %d entries after stress, want "+
+				"1 to %d", test.name, n, test.maxLen)
+		}
+		if stats := sigCache.Stats(); stats.Evictions == 0 {
+			t.Fatalf("%s: no entries were evicted", test.name)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/navcoin/navd/btcec"
+	"github.com/navcoin/navd/chaincfg/chainhash"
+	"github.com/navcoin/navd/wire"
+)
+
+const (
+	// sigCacheDumpVersion is the version of the format written by Dump.
+	sigCacheDumpVersion = 1
+
+	// sigCacheDumpHeaderLen is the length of the header of a dump, which
+	// consists of the magic, the version and the number of entries.
+	sigCacheDumpHeaderLen = 4 + 4 + 4
+
+	// sigCacheDumpChecksumLen is the length of the checksum which follows
+	// the entries of a dump.
+	sigCacheDumpChecksumLen = 4
+
+	// maxSigCacheDumpEntries is the maximum number of entries Load accepts
+	// in a dump.
+	maxSigCacheDumpEntries = 1 << 20
+
+	// maxSigCacheDumpItemLen is the maximum length of the serialized
+	// signatures and public keys of the Schnorr entries in a dump.
+	maxSigCacheDumpItemLen = 80
+
+	// maxSigCacheDumpEntryLen is the maximum length of a serialized entry,
+	// which consists of its type, flags and sigHash followed by either a
+	// fixed length ECDSA signature and compressed public key or the
+	// length prefixed Schnorr signature and public key.
+	maxSigCacheDumpEntryLen = 2 + chainhash.HashSize +
+		2*(1+maxSigCacheDumpItemLen)
+
+	// maxSigCacheDumpLen is the maximum length of a dump Load accepts.
+	maxSigCacheDumpLen = sigCacheDumpHeaderLen + sigCacheDumpChecksumLen +
+		maxSigCacheDumpEntries*maxSigCacheDumpEntryLen
+
+	// sigCacheDumpScalarLen is the length of the R and S values of the
+	// ECDSA signatures in a dump.
+	sigCacheDumpScalarLen = 32
+)
+
+// sigCacheDumpMagic identifies the data written by Dump.
+var sigCacheDumpMagic = [4]byte{'n', 'v', 's', 'c'}
+
+// dumpError returns an error which describes why a dump is invalid.
+func dumpError(format string, args ...interface{}) error {
+	return fmt.Errorf("invalid signature cache dump: "+format, args...)
+}
+
+// sigCacheDumpEntry is an entry read from a dump along with its sigHash.
+type sigCacheDumpEntry struct {
+	sigHash chainhash.Hash
+	entry   *sigCacheEntry
+}
+
+// Dump writes every entry of the signature cache, apart from expired ones, to
+// the passed writer so the cache can be warmed up with Load after a restart.
+// Both ECDSA and Schnorr entries are written along with the flags they were
+// added with.  Signatures marked as invalid with MarkInvalid are not written,
+// and neither are Schnorr entries with items longer than Load accepts nor any
+// entries beyond the maximum number of entries of a dump.
+//
+// The dump starts with a magic and version header and ends with a checksum so
+// that Load is able to reject data which is not a dump or was truncated or
+// corrupted.
+//
+// The entries are serialized while holding the read lock, which takes time
+// linear in the number of entries, so it must not be called in performance
+// critical paths such as transaction validation.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *SigCache) Dump(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.Write(sigCacheDumpMagic[:])
+	var scratch [4]byte
+	binary.LittleEndian.PutUint32(scratch[:], sigCacheDumpVersion)
+	buf.Write(scratch[:])
+	buf.Write(scratch[:]) // Placeholder for the number of entries.
+
+	s.RLock()
+	var count uint32
+	for sigHash, entries := range s.validSigs {
+		for _, entry := range entries {
+			if count == maxSigCacheDumpEntries {
+				break
+			}
+			if s.expired(entry) || !dumpable(entry) {
+				continue
+			}
+			writeDumpEntry(&buf, &sigHash, entry)
+			count++
+		}
+	}
+	s.RUnlock()
+
+	dump := buf.Bytes()
+	binary.LittleEndian.PutUint32(dump[8:sigCacheDumpHeaderLen], count)
+	checksum := chainhash.MessageChecksum(dump)
+	buf.Write(checksum[:])
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// dumpable returns whether the passed entry can be written to a dump, which is
+// only not the case for Schnorr entries with an oversized signature or public
+// key.
+func dumpable(entry *sigCacheEntry) bool {
+	if entry.sigType != sigTypeSchnorr {
+		return true
+	}
+	return entry.sigLen <= maxSigCacheDumpItemLen &&
+		len(entry.raw)-entry.sigLen <= maxSigCacheDumpItemLen
+}
+
+// writeDumpEntry serializes the passed entry over the passed sigHash to the
+// passed buffer in the format read by readDumpEntry.
+func writeDumpEntry(buf *bytes.Buffer, sigHash *chainhash.Hash, entry *sigCacheEntry) {
+	buf.WriteByte(byte(entry.sigType))
+	buf.WriteByte(byte(entry.flags))
+	buf.Write(sigHash[:])
+
+	if entry.sigType == sigTypeSchnorr {
+		wire.WriteVarBytes(buf, 0, entry.raw[:entry.sigLen])
+		wire.WriteVarBytes(buf, 0, entry.raw[entry.sigLen:])
+		return
+	}
+
+	// The R and S values are written as is rather than DER encoded, since
+	// the encoding normalizes S, and the entry only matches the exact
+	// signature it was added for.
+	var scalar [sigCacheDumpScalarLen]byte
+	for _, v := range []*big.Int{entry.sig.R, entry.sig.S} {
+		b := v.Bytes()
+		for i := range scalar {
+			scalar[i] = 0
+		}
+		copy(scalar[len(scalar)-len(b):], b)
+		buf.Write(scalar[:])
+	}
+	buf.Write(entry.pubKey.SerializeCompressed())
+}
+
+// readDumpEntry reads an entry in the format written by writeDumpEntry from
+// the passed reader.
+func readDumpEntry(r *bytes.Reader) (*sigCacheDumpEntry, error) {
+	var header [2 + chainhash.HashSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	sigType := sigCacheSigType(header[0])
+	flags := SigCacheFlags(header[1])
+	var sigHash chainhash.Hash
+	copy(sigHash[:], header[2:])
+
+	switch sigType {
+	case sigTypeSchnorr:
+		sig, err := wire.ReadVarBytes(r, 0, maxSigCacheDumpItemLen,
+			"signature")
+		if err != nil {
+			return nil, err
+		}
+		pubKey, err := wire.ReadVarBytes(r, 0, maxSigCacheDumpItemLen,
+			"public key")
+		if err != nil {
+			return nil, err
+		}
+		entry := newSchnorrEntry(sig, pubKey)
+		entry.flags = flags
+		return &sigCacheDumpEntry{sigHash: sigHash, entry: entry}, nil
+
+	case sigTypeECDSA:
+		var b [2*sigCacheDumpScalarLen + btcec.PubKeyBytesLenCompressed]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		sigBytes := b[:2*sigCacheDumpScalarLen]
+		sig := &btcec.Signature{
+			R: new(big.Int).SetBytes(sigBytes[:sigCacheDumpScalarLen]),
+			S: new(big.Int).SetBytes(sigBytes[sigCacheDumpScalarLen:]),
+		}
+		order := btcec.S256().N
+		if sig.R.Sign() == 0 || sig.R.Cmp(order) >= 0 ||
+			sig.S.Sign() == 0 || sig.S.Cmp(order) >= 0 {
+
+			return nil, dumpError("signature out of range")
+		}
+		pubKey, err := btcec.ParsePubKey(b[len(sigBytes):], btcec.S256())
+		if err != nil {
+			return nil, dumpError("%v", err)
+		}
+		entry := newECDSAEntry(sig, pubKey, flags)
+		return &sigCacheDumpEntry{sigHash: sigHash, entry: entry}, nil
+	}
+
+	return nil, dumpError("unknown signature type %d", sigType)
+}
+
+// Load reads a dump written by Dump from the passed reader and adds its entries
+// to the signature cache, evicting existing entries when the cache fills up
+// like Add.  Entries are only added once the whole dump was read and verified,
+// so nothing is added when an error is returned, such as for data which is
+// truncated, corrupt, of another version or larger than the maximum size of a
+// dump.
+//
+// Since the cache is trusted to only hold valid signatures, a dump must only
+// be loaded without verification when it is read from storage which can't be
+// tampered with.  When 'verify' is true, every ECDSA signature is verified
+// against its sigHash and public key before it is added, and the dump is
+// rejected when any of them is invalid.  Schnorr entries can't be verified by
+// this package, so they are skipped instead.
+//
+// NOTE: This function is safe for concurrent access. Writers will block
+// simultaneous readers until function execution has concluded.
+func (s *SigCache) Load(r io.Reader, verify bool) error {
+	dump, err := ioutil.ReadAll(io.LimitReader(r, maxSigCacheDumpLen+1))
+	if err != nil {
+		return err
+	}
+	if len(dump) > maxSigCacheDumpLen {
+		return dumpError("dump exceeds the maximum length of %d bytes",
+			maxSigCacheDumpLen)
+	}
+	if len(dump) < sigCacheDumpHeaderLen+sigCacheDumpChecksumLen {
+		return dumpError("dump is truncated")
+	}
+
+	payload := dump[:len(dump)-sigCacheDumpChecksumLen]
+	checksum := chainhash.MessageChecksum(payload)
+	if !bytes.Equal(checksum[:], dump[len(payload):]) {
+		return dumpError("checksum mismatch")
+	}
+	if !bytes.Equal(payload[:4], sigCacheDumpMagic[:]) {
+		return dumpError("unknown magic %x", payload[:4])
+	}
+	version := binary.LittleEndian.Uint32(payload[4:8])
+	if version != sigCacheDumpVersion {
+		return dumpError("unsupported version %d", version)
+	}
+	count := binary.LittleEndian.Uint32(payload[8:sigCacheDumpHeaderLen])
+	if count > maxSigCacheDumpEntries {
+		return dumpError("dump holds %d entries, more than the maximum "+
+			"of %d", count, maxSigCacheDumpEntries)
+	}
+
+	reader := bytes.NewReader(payload[sigCacheDumpHeaderLen:])
+	var entries []*sigCacheDumpEntry
+	for i := uint32(0); i < count; i++ {
+		entry, err := readDumpEntry(reader)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return dumpError("dump is truncated")
+			}
+			if _, ok := err.(*wire.MessageError); ok {
+				return dumpError("%v", err)
+			}
+			return err
+		}
+
+		if verify {
+			if entry.entry.sigType != sigTypeECDSA {
+				continue
+			}
+			if !entry.entry.sig.Verify(entry.sigHash[:],
+				entry.entry.pubKey) {
+
+				return dumpError("invalid signature for sighash %v",
+					entry.sigHash)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	if reader.Len() != 0 {
+		return dumpError("%d trailing bytes after the last entry",
+			reader.Len())
+	}
+
+	s.Lock()
+	for _, entry := range entries {
+		s.add(entry.sigHash, entry.entry)
+	}
+	s.Unlock()
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"testing"
+
+	"github.com/navcoin/navd/btcec"
+	"github.com/navcoin/navd/chaincfg/chainhash"
+)
+
+// dumpTestEntry is an ECDSA entry used to test dumping and loading a signature
+// cache.
+type dumpTestEntry struct {
+	msg *chainhash.Hash
+	sig *btcec.Signature
+	key *btcec.PublicKey
+}
+
+// genDumpTestEntries returns the passed number of random ECDSA entries with
+// valid signatures.  The signature of the first one has a high S value.
+func genDumpTestEntries(t *testing.T, n int) []dumpTestEntry {
+	entries := make([]dumpTestEntry, n)
+	for i := range entries {
+		msg, sig, key, err := genRandomSig()
+		if err != nil {
+			t.Fatalf("unable to generate random signature test data")
+		}
+		entries[i] = dumpTestEntry{msg, sig, key}
+	}
+	highS := new(big.Int).Sub(btcec.S256().N, entries[0].sig.S)
+	entries[0].sig = &btcec.Signature{R: entries[0].sig.R, S: highS}
+	return entries
+}
+
+// TestSigCacheDumpLoad tests that the entries of a signature cache survive a
+// round trip through Dump and Load, both with and without verification.
+func TestSigCacheDumpLoad(t *testing.T) {
+	entries := genDumpTestEntries(t, 3)
+	schnorrHash := chainhash.Hash{0x01}
+	schnorrSig, schnorrKey := bytes.Repeat([]byte{0x02}, 64),
+		bytes.Repeat([]byte{0x03}, 32)
+
+	sigCache := NewSigCache(10)
+	for i, entry := range entries {
+		var flags SigCacheFlags
+		if i == 1 {
+			flags = SigCacheEphemeral
+		}
+		sigCache.AddWithFlags(*entry.msg, entry.sig, entry.key, flags)
+	}
+	sigCache.AddSchnorr(schnorrHash, schnorrSig, schnorrKey)
+
+	var dump bytes.Buffer
+	if err := sigCache.Dump(&dump); err != nil {
+		t.Fatalf("Dump: unexpected error: %v", err)
+	}
+
+	for _, verify := range []bool{false, true} {
+		loaded := NewSigCache(10)
+		if err := loaded.Load(bytes.NewReader(dump.Bytes()), verify); err != nil {
+			t.Fatalf("Load (verify %v): unexpected error: %v", verify,
+				err)
+		}
+		for i, entry := range entries {
+			if !loaded.Exists(*entry.msg, entry.sig, entry.key) {
+				t.Errorf("Load (verify %v): entry #%d not found",
+					verify, i)
+			}
+		}
+		flags := loaded.validSigs[*entries[1].msg][0].flags
+		if flags != SigCacheEphemeral {
+			t.Errorf("Load (verify %v): entry has flags %v, want %v",
+				verify, flags, SigCacheEphemeral)
+		}
+
+		// Schnorr entries can't be verified, so they are only loaded
+		// without verification.
+		got := loaded.ExistsSchnorr(schnorrHash, schnorrSig, schnorrKey)
+		if got != !verify {
+			t.Errorf("Load (verify %v): Schnorr entry exists = %v, "+
+				"want %v", verify, got, !verify)
+		}
+	}
+}
+
+// TestSigCacheLoadInvalid tests that Load rejects dumps which are truncated,
+// corrupt or hold invalid signatures when verifying them, without adding any
+// of their entries.
+func TestSigCacheLoadInvalid(t *testing.T) {
+	entries := genDumpTestEntries(t, 2)
+	sigCache := NewSigCache(10)
+	for _, entry := range entries {
+		sigCache.Add(*entry.msg, entry.sig, entry.key)
+	}
+	var buf bytes.Buffer
+	if err := sigCache.Dump(&buf); err != nil {
+		t.Fatalf("Dump: unexpected error: %v", err)
+	}
+	dump := buf.Bytes()
+
+	// withChecksum returns the passed dump modified by the passed function
+	// with a valid checksum, so that it is only rejected for the
+	// modification.
+	withChecksum := func(modify func(b []byte) []byte) []byte {
+		b := append([]byte(nil), dump[:len(dump)-sigCacheDumpChecksumLen]...)
+		b = modify(b)
+		checksum := chainhash.MessageChecksum(b)
+		return append(b, checksum[:]...)
+	}
+	flipped := append([]byte(nil), dump...)
+	flipped[sigCacheDumpHeaderLen+10] ^= 0x01
+
+	tests := []struct {
+		name string
+		dump []byte
+	}{
+		{"empty", nil},
+		{"header only", dump[:sigCacheDumpHeaderLen]},
+		{"missing checksum byte", dump[:len(dump)-1]},
+		{"truncated entry", withChecksum(func(b []byte) []byte {
+			return b[:len(b)-1]
+		})},
+		{"flipped bit", flipped},
+		{"bad magic", withChecksum(func(b []byte) []byte {
+			b[0] ^= 0xff
+			return b
+		})},
+		{"bad version", withChecksum(func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[4:], sigCacheDumpVersion+1)
+			return b
+		})},
+		{"too many entries", withChecksum(func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[8:],
+				maxSigCacheDumpEntries+1)
+			return b
+		})},
+		{"trailing bytes", withChecksum(func(b []byte) []byte {
+			return append(b, 0x00)
+		})},
+		{"unknown type", withChecksum(func(b []byte) []byte {
+			b[sigCacheDumpHeaderLen] = 0xff
+			return b
+		})},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		loaded := NewSigCache(10)
+		if err := loaded.Load(bytes.NewReader(test.dump), false); err == nil {
+			t.Errorf("Load (%s): dump was not rejected", test.name)
+		}
+		if n := loaded.Len(); n != 0 {
+			t.Errorf("Load (%s): cache holds %d entries, want 0",
+				test.name, n)
+		}
+	}
+
+	// A dump with a signature which is not valid for its sigHash must only
+	// be rejected when verifying it.
+	sigCache = NewSigCache(10)
+	sigCache.Add(*entries[0].msg, entries[0].sig, entries[0].key)
+	sigCache.Add(chainhash.Hash{0x01}, entries[1].sig, entries[1].key)
+	buf.Reset()
+	if err := sigCache.Dump(&buf); err != nil {
+		t.Fatalf("Dump: unexpected error: %v", err)
+	}
+	loaded := NewSigCache(10)
+	if err := loaded.Load(bytes.NewReader(buf.Bytes()), true); err == nil {
+		t.Fatalf("Load: dump with invalid signature was not rejected")
+	}
+	if n := loaded.Len(); n != 0 {
+		t.Fatalf("Load: cache holds %d entries, want 0", n)
+	}
+	if err := loaded.Load(bytes.NewReader(buf.Bytes()), false); err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if n := loaded.Len(); n != 2 {
+		t.Fatalf("Load: cache holds %d entries, want 2", n)
+	}
+}
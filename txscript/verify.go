@@ -0,0 +1,239 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/navcoin/navd/chaincfg/chainhash"
+	"github.com/navcoin/navd/wire"
+)
+
+// PrevOutputFetcher provides the outputs spent by transaction inputs so their
+// scripts can be verified.
+type PrevOutputFetcher interface {
+	// FetchPrevOutput returns the output referenced by the passed
+	// outpoint or nil when it is not known.
+	FetchPrevOutput(op wire.OutPoint) *wire.TxOut
+}
+
+// scriptVerifyItem holds a transaction along with which input to verify.
+type scriptVerifyItem struct {
+	tx        *wire.MsgTx
+	txIdx     int
+	sigHashes *TxSigHashes
+}
+
+// verifyInput executes the script pair of the passed transaction input against
+// the output it spends.
+func verifyInput(item *scriptVerifyItem, prevOuts PrevOutputFetcher,
+	flags ScriptFlags, sigCache SignatureCache, scriptCache *ScriptCache,
+	resultCache *ScriptResultCache) error {
+
+	txIn := item.tx.TxIn[item.txIdx]
+	prevOut := prevOuts.FetchPrevOutput(txIn.PreviousOutPoint)
+	if prevOut == nil {
+		str := fmt.Sprintf("unable to find output %v referenced from "+
+			"transaction %v:%d", txIn.PreviousOutPoint,
+			item.tx.TxHash(), item.txIdx)
+		return scriptError(ErrMissingPrevOut, str)
+	}
+
+	// Reject script pairs which are already known to be invalid without
+	// creating an engine for them.
+	var err error
+	if scriptCache != nil {
+		err = scriptCache.Lookup(txIn.SignatureScript, prevOut.PkScript,
+			flags)
+	}
+
+	// Accept inputs which are already known to be valid in this exact
+	// context without running the engine.  There is no key for public key
+	// scripts which don't parse, which the engine rejects below.
+	var resultKey *chainhash.Hash
+	if err == nil && resultCache != nil {
+		key, keyErr := ScriptResultKey(item.tx, item.txIdx,
+			prevOut.PkScript, prevOut.Value, flags)
+		if keyErr == nil {
+			if valid, ok := resultCache.Lookup(key); ok && valid {
+				return nil
+			}
+			resultKey = &key
+		}
+	}
+
+	if err == nil {
+		var vm *Engine
+		vm, err = NewEngine(prevOut.PkScript, item.tx, item.txIdx,
+			flags, sigCache, item.sigHashes, prevOut.Value)
+		if err == nil {
+			err = vm.Execute()
+		} else if scriptCache != nil {
+			scriptCache.Add(txIn.SignatureScript, prevOut.PkScript,
+				flags, err)
+		}
+	}
+	if err != nil {
+		str := fmt.Sprintf("failed to validate input %v:%d which "+
+			"references output %v - %v", item.tx.TxHash(),
+			item.txIdx, txIn.PreviousOutPoint, err)
+		if serr, ok := err.(Error); ok {
+			return scriptError(serr.ErrorCode, str)
+		}
+		return errors.New(str)
+	}
+
+	if resultKey != nil {
+		resultCache.Add(*resultKey, true)
+	}
+	return nil
+}
+
+// VerifyBlockScripts verifies the scripts of all inputs of the passed
+// transactions, except those of coinbases, against the outputs they spend as
+// provided by prevOuts, using the passed script flags.
+//
+// The inputs are distributed across a pool of the passed number of worker
+// goroutines, which all consult and populate the same signature cache.  The
+// number of workers defaults to GOMAXPROCS when it is zero or negative.  The
+// signature cache may be nil to disable caching.
+//
+// The script cache, which may also be nil, records the script pairs which are
+// found to be invalid regardless of the spending transaction so that inputs
+// spending them again are rejected without creating a script engine.
+//
+// The script result cache, which may also be nil, records the inputs which are
+// found to be valid so that verifying them again, for instance when a block
+// includes transactions already verified in the mempool, skips the script
+// engine entirely.  Only valid verdicts are recorded so that failures are
+// always reported with the error which caused them.
+//
+// As soon as any input fails verification, no further inputs are handed to the
+// workers, and the error of that input is returned once the workers have
+// finished.
+func VerifyBlockScripts(txs []*wire.MsgTx, prevOuts PrevOutputFetcher,
+	flags ScriptFlags, sigCache SignatureCache, scriptCache *ScriptCache,
+	resultCache *ScriptResultCache, workers int) error {
+
+	return VerifyWithContext(context.Background(), txs, prevOuts, flags,
+		sigCache, scriptCache, resultCache, workers)
+}
+
+// VerifyWithContext verifies the scripts of the inputs of the passed
+// transactions like VerifyBlockScripts, but stops once the passed context is
+// done.  The context is checked before each input is verified, and its error
+// is returned as soon as the inputs being verified at that time have finished,
+// which allows long running verification, such as that of the verifychain RPC,
+// to be aborted.
+//
+// The caches are consulted and populated for every input which is verified
+// before the context is done, exactly as by VerifyBlockScripts.  Since an input
+// is either verified completely or not at all, canceling the context never
+// leaves partial results in them.
+func VerifyWithContext(ctx context.Context, txs []*wire.MsgTx,
+	prevOuts PrevOutputFetcher, flags ScriptFlags, sigCache SignatureCache,
+	scriptCache *ScriptCache, resultCache *ScriptResultCache,
+	workers int) error {
+
+	// Collect all of the transaction inputs to verify.  The signature hash
+	// midstates are only needed for transactions with witness data when
+	// segwit is active, and then are shared by all of their inputs.
+	segwitActive := flags&ScriptVerifyWitness == ScriptVerifyWitness
+	var items []*scriptVerifyItem
+	for _, tx := range txs {
+		var sigHashes *TxSigHashes
+		if segwitActive && tx.HasWitness() {
+			sigHashes = NewTxSigHashes(tx)
+		}
+
+		for txIdx, txIn := range tx.TxIn {
+			// Skip coinbases.
+			if txIn.PreviousOutPoint.Index == math.MaxUint32 {
+				continue
+			}
+
+			items = append(items, &scriptVerifyItem{
+				tx:        tx,
+				txIdx:     txIdx,
+				sigHashes: sigHashes,
+			})
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(items) {
+		workers = len(items)
+	}
+
+	// The quit channel is closed when the first error occurs, including the
+	// context being done, so that all workers exit and no further items
+	// are sent.
+	var (
+		wg       sync.WaitGroup
+		failOnce sync.Once
+		failErr  error
+	)
+	itemChan := make(chan *scriptVerifyItem)
+	quit := make(chan struct{})
+	fail := func(err error) {
+		failOnce.Do(func() {
+			failErr = err
+			close(quit)
+		})
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case item, ok := <-itemChan:
+					if !ok {
+						return
+					}
+					if err := ctx.Err(); err != nil {
+						fail(err)
+						return
+					}
+					err := verifyInput(item, prevOuts, flags,
+						sigCache, scriptCache, resultCache)
+					if err != nil {
+						fail(err)
+						return
+					}
+
+				case <-quit:
+					return
+				}
+			}
+		}()
+	}
+
+out:
+	for _, item := range items {
+		select {
+		case itemChan <- item:
+		case <-ctx.Done():
+			fail(ctx.Err())
+			break out
+		case <-quit:
+			break out
+		}
+	}
+	close(itemChan)
+	wg.Wait()
+
+	return failErr
+}
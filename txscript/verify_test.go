@@ -0,0 +1,273 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txscript
+
+import (
+	"context"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/navcoin/navd/btcec"
+	"github.com/navcoin/navd/chaincfg/chainhash"
+	"github.com/navcoin/navd/wire"
+)
+
+// testPrevOuts is a PrevOutputFetcher backed by a map which counts the number
+// of outputs fetched.
+type testPrevOuts struct {
+	sync.Mutex
+	outputs map[wire.OutPoint]*wire.TxOut
+	fetches int
+}
+
+// FetchPrevOutput returns the output referenced by the passed outpoint.
+func (p *testPrevOuts) FetchPrevOutput(op wire.OutPoint) *wire.TxOut {
+	p.Lock()
+	p.fetches++
+	p.Unlock()
+	return p.outputs[op]
+}
+
+// genVerifyTxns returns the requested number of transactions, each of which
+// spends a pay-to-pubkey output with a valid signature, along with the outputs
+// they spend.  The returned transactions are preceded by a coinbase.
+func genVerifyTxns(t *testing.T, count int) ([]*wire.MsgTx, *testPrevOuts) {
+	privKey, err := btcec.NewPrivateKey(btcec.S256())
+	if err != nil {
+		t.Fatalf("unable to generate private key: %v", err)
+	}
+	pkScript, err := NewScriptBuilder().
+		AddData(privKey.PubKey().SerializeCompressed()).
+		AddOp(OP_CHECKSIG).Script()
+	if err != nil {
+		t.Fatalf("unable to build pkScript: %v", err)
+	}
+
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(wire.NewTxIn(wire.NewOutPoint(&chainhash.Hash{},
+		math.MaxUint32), []byte{0x51}, nil))
+	coinbase.AddTxOut(wire.NewTxOut(1, pkScript))
+
+	prevOuts := &testPrevOuts{outputs: make(map[wire.OutPoint]*wire.TxOut)}
+	txns := []*wire.MsgTx{coinbase}
+	for i := 0; i < count; i++ {
+		prevOut := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: uint32(i)}
+		prevOuts.outputs[prevOut] = wire.NewTxOut(int64(i), pkScript)
+
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.AddTxIn(wire.NewTxIn(&prevOut, nil, nil))
+		tx.AddTxOut(wire.NewTxOut(int64(i), pkScript))
+		sig, err := RawTxInSignature(tx, 0, pkScript, SigHashAll, privKey)
+		if err != nil {
+			t.Fatalf("unable to sign transaction: %v", err)
+		}
+		tx.TxIn[0].SignatureScript, err = NewScriptBuilder().
+			AddData(sig).Script()
+		if err != nil {
+			t.Fatalf("unable to build sigScript: %v", err)
+		}
+		txns = append(txns, tx)
+	}
+
+	return txns, prevOuts
+}
+
+// TestVerifyBlockScripts ensures VerifyBlockScripts accepts valid inputs for
+// any number of workers while populating the shared signature cache and that
+// the first failure is returned and halts verification.
+func TestVerifyBlockScripts(t *testing.T) {
+	const numTxns = 50
+	const flags = ScriptBip16 | ScriptVerifyDERSignatures |
+		ScriptVerifyStrictEncoding
+
+	txns, prevOuts := genVerifyTxns(t, numTxns)
+	for _, workers := range []int{-1, 0, 1, 4, numTxns * 2} {
+		sigCache := NewSigCache(numTxns)
+		err := VerifyBlockScripts(txns, prevOuts, flags, sigCache,
+			nil, nil, workers)
+		if err != nil {
+			t.Fatalf("VerifyBlockScripts (%d workers): unexpected "+
+				"error: %v", workers, err)
+		}
+		if n := sigCache.Len(); n != numTxns {
+			t.Fatalf("VerifyBlockScripts (%d workers): sig cache "+
+				"holds %d entries, want %d", workers, n, numTxns)
+		}
+	}
+	err := VerifyBlockScripts(txns, prevOuts, flags, nil, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("VerifyBlockScripts (no sig cache): unexpected error: "+
+			"%v", err)
+	}
+	err = VerifyBlockScripts(nil, prevOuts, flags, nil, nil, nil, 0)
+	if err != nil {
+		t.Fatalf("VerifyBlockScripts (no txns): unexpected error: %v",
+			err)
+	}
+
+	// Spending an output which requires a false top stack element must
+	// fail.  Since it is the first input, a single worker must stop after
+	// it and multiple workers must stop before verifying every input.
+	txns, prevOuts = genVerifyTxns(t, numTxns)
+	badOut := txns[1].TxIn[0].PreviousOutPoint
+	prevOuts.outputs[badOut] = wire.NewTxOut(0, []byte{OP_FALSE})
+	for _, test := range []struct {
+		workers    int
+		maxFetches int
+	}{
+		{1, 1},
+		{4, numTxns - 1},
+	} {
+		prevOuts.fetches = 0
+		err := VerifyBlockScripts(txns, prevOuts, flags, nil, nil,
+			nil, test.workers)
+		if !IsErrorCode(err, ErrEvalFalse) {
+			t.Fatalf("VerifyBlockScripts (%d workers): got error "+
+				"%v, want %v", test.workers, err, ErrEvalFalse)
+		}
+		if prevOuts.fetches > test.maxFetches {
+			t.Fatalf("VerifyBlockScripts (%d workers): fetched %d "+
+				"outputs, want at most %d", test.workers,
+				prevOuts.fetches, test.maxFetches)
+		}
+	}
+
+	// Spending an unknown output must fail.
+	delete(prevOuts.outputs, badOut)
+	err = VerifyBlockScripts(txns, prevOuts, flags, nil, nil, nil, 4)
+	if !IsErrorCode(err, ErrMissingPrevOut) {
+		t.Fatalf("VerifyBlockScripts: got error %v, want %v", err,
+			ErrMissingPrevOut)
+	}
+}
+
+// cancelingPrevOuts is a PrevOutputFetcher which cancels a context once a given
+// number of outputs have been fetched.
+type cancelingPrevOuts struct {
+	*testPrevOuts
+	cancel      context.CancelFunc
+	cancelAfter int
+}
+
+// FetchPrevOutput returns the output referenced by the passed outpoint and
+// cancels the context once enough outputs have been fetched.
+func (p *cancelingPrevOuts) FetchPrevOutput(op wire.OutPoint) *wire.TxOut {
+	txOut := p.testPrevOuts.FetchPrevOutput(op)
+	p.Lock()
+	if p.fetches == p.cancelAfter {
+		p.cancel()
+	}
+	p.Unlock()
+	return txOut
+}
+
+// TestVerifyWithContext ensures VerifyWithContext stops verifying inputs once
+// its context is canceled, returns the cancellation error and leaves the
+// signature cache holding exactly the signatures which were verified.
+func TestVerifyWithContext(t *testing.T) {
+	const numTxns = 50
+	const cancelAfter = 10
+	const flags = ScriptBip16 | ScriptVerifyDERSignatures |
+		ScriptVerifyStrictEncoding
+
+	txns, prevOuts := genVerifyTxns(t, numTxns)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fetcher := &cancelingPrevOuts{
+		testPrevOuts: prevOuts,
+		cancel:       cancel,
+		cancelAfter:  cancelAfter,
+	}
+
+	// With a single worker, the input being verified when the context is
+	// canceled is still completed, but no further input is verified.
+	sigCache := NewSigCache(numTxns)
+	err := VerifyWithContext(ctx, txns, fetcher, flags, sigCache, nil, nil,
+		1)
+	if err != context.Canceled {
+		t.Fatalf("VerifyWithContext: got error %v, want %v", err,
+			context.Canceled)
+	}
+	if prevOuts.fetches != cancelAfter {
+		t.Fatalf("VerifyWithContext: fetched %d outputs, want %d",
+			prevOuts.fetches, cancelAfter)
+	}
+	if n := sigCache.Len(); n != cancelAfter {
+		t.Fatalf("VerifyWithContext: sig cache holds %d entries, want "+
+			"%d", n, cancelAfter)
+	}
+
+	// Verifying with a canceled context must not verify anything.
+	prevOuts.fetches = 0
+	err = VerifyWithContext(ctx, txns, prevOuts, flags, sigCache, nil, nil,
+		4)
+	if err != context.Canceled {
+		t.Fatalf("VerifyWithContext (canceled): got error %v, want %v",
+			err, context.Canceled)
+	}
+	if prevOuts.fetches != 0 {
+		t.Fatalf("VerifyWithContext (canceled): fetched %d outputs",
+			prevOuts.fetches)
+	}
+
+	// The cache must still be usable and complete once every input is
+	// verified.
+	err = VerifyWithContext(context.Background(), txns, prevOuts, flags,
+		sigCache, nil, nil, 4)
+	if err != nil {
+		t.Fatalf("VerifyWithContext: unexpected error: %v", err)
+	}
+	if n := sigCache.Len(); n != numTxns {
+		t.Fatalf("VerifyWithContext: sig cache holds %d entries, want "+
+			"%d", n, numTxns)
+	}
+}
+
+// TestVerifyBlockScriptsFlags ensures VerifyBlockScripts enforces the rules
+// selected by the passed flags, and only those, by verifying a script which
+// leaves extra items on the stack with and without the clean stack rule.
+func TestVerifyBlockScriptsFlags(t *testing.T) {
+	prevOut := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 0}
+	prevOuts := &testPrevOuts{outputs: map[wire.OutPoint]*wire.TxOut{
+		prevOut: wire.NewTxOut(1, []byte{OP_TRUE}),
+	}}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(wire.NewTxIn(&prevOut, []byte{OP_1, OP_1}, nil))
+	tx.AddTxOut(wire.NewTxOut(1, []byte{OP_TRUE}))
+	txns := []*wire.MsgTx{tx}
+
+	tests := []struct {
+		name  string
+		flags ScriptFlags
+		err   ErrorCode
+		valid bool
+	}{
+		{"no flags", 0, 0, true},
+		{"p2sh", ScriptBip16, 0, true},
+		{"strict encoding and der", ScriptBip16 |
+			ScriptVerifyStrictEncoding | ScriptVerifyDERSignatures, 0,
+			true},
+		{"clean stack", ScriptBip16 | ScriptVerifyCleanStack,
+			ErrCleanStack, false},
+		{"clean stack without p2sh", ScriptVerifyCleanStack,
+			ErrInvalidFlags, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		err := VerifyBlockScripts(txns, prevOuts, test.flags, nil, nil,
+			nil, 1)
+		if (err == nil) != test.valid {
+			t.Errorf("VerifyBlockScripts (%s): unexpected error "+
+				"state: %v", test.name, err)
+			continue
+		}
+		if !test.valid && !IsErrorCode(err, test.err) {
+			t.Errorf("VerifyBlockScripts (%s): got error %v, want "+
+				"%v", test.name, err, test.err)
+		}
+	}
+}
@@ -46,18 +46,14 @@ const blockHeaderLen = 80
 
 // BlockHash computes the block identifier hash for the given block header.
 func (h *BlockHeader) BlockHash() chainhash.Hash {
-	// Encode the header and double sha256 everything prior to the number of
+	// Encode the header and hash everything prior to the number of
 	// transactions.  Ignore the error returns since there is no way the
 	// encode could fail except being out of memory which would cause a
 	// run-time panic.
 	buf := bytes.NewBuffer(make([]byte, 0, MaxBlockHeaderPayload))
 	_ = writeBlockHeader(buf, 0, h)
 
-	if h.Version > 6 {
-		return chainhash.DoubleHashH(buf.Bytes())
-	} else {
-		return chainhash.X13HashH(buf.Bytes())
-	}
+	return chainhash.BlockHash(buf.Bytes())
 }
 
 // BtcDecode decodes r using the navcoin protocol encoding into the receiver.
@@ -306,7 +306,7 @@ func WriteMessageWithEncodingN(w io.Writer, msg Message, pver uint32,
 	hdr.magic = navnet
 	hdr.command = cmd
 	hdr.length = uint32(lenp)
-	copy(hdr.checksum[:], chainhash.DoubleHashB(payload)[0:4])
+	hdr.checksum = chainhash.MessageChecksum(payload)
 
 	// Encode the header for the message.  This is done to a buffer
 	// rather than directly to the writer since writeElements doesn't
@@ -396,8 +396,8 @@ func ReadMessageWithEncodingN(r io.Reader, pver uint32, navnet NavCoinNet,
 	}
 
 	// Test checksum.
-	checksum := chainhash.DoubleHashB(payload)[0:4]
-	if !bytes.Equal(checksum[:], hdr.checksum[:]) {
+	checksum := chainhash.MessageChecksum(payload)
+	if checksum != hdr.checksum {
 		str := fmt.Sprintf("payload checksum failed - header "+
 			"indicates %v, but actual checksum is %v.",
 			hdr.checksum, checksum)
@@ -97,6 +97,48 @@ func TestAddr(t *testing.T) {
 	}
 }
 
+// TestAddrMultipleBoundary tests that addr messages with multiple addresses can
+// only be encoded starting at the first protocol version which allows them.
+func TestAddrMultipleBoundary(t *testing.T) {
+	na := NewNetAddressIPPort(net.ParseIP("127.0.0.1"), 8333, SFNodeNetwork)
+	msg := NewMsgAddr()
+	msg.AddAddresses(na, na)
+
+	var buf bytes.Buffer
+	err := msg.BtcEncode(&buf, MultipleAddressVersion-1, BaseEncoding)
+	if _, ok := err.(*MessageError); !ok {
+		t.Fatalf("BtcEncode (pver %d): got error %v, want MessageError",
+			MultipleAddressVersion-1, err)
+	}
+
+	buf.Reset()
+	err = msg.BtcEncode(&buf, MultipleAddressVersion, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcEncode (pver %d): unexpected error: %v",
+			MultipleAddressVersion, err)
+	}
+	var decoded MsgAddr
+	err = decoded.BtcDecode(&buf, MultipleAddressVersion, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcDecode (pver %d): unexpected error: %v",
+			MultipleAddressVersion, err)
+	}
+	if len(decoded.AddrList) != 2 {
+		t.Fatalf("BtcDecode (pver %d): got %d addresses, want 2",
+			MultipleAddressVersion, len(decoded.AddrList))
+	}
+
+	// A single address may be encoded at any protocol version.
+	msg.ClearAddresses()
+	msg.AddAddress(na)
+	buf.Reset()
+	err = msg.BtcEncode(&buf, MultipleAddressVersion-1, BaseEncoding)
+	if err != nil {
+		t.Fatalf("BtcEncode (pver %d): unexpected error: %v",
+			MultipleAddressVersion-1, err)
+	}
+}
+
 // TestAddrWire tests the MsgAddr wire encode and decode for various numbers
 // of addresses and protocol versions.
 func TestAddrWire(t *testing.T) {
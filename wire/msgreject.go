@@ -15,16 +15,39 @@ import (
 // why a message was rejected.
 type RejectCode uint8
 
-// These constants define the various supported reject codes.
+// These constants define the various supported reject codes.  Their numeric
+// values are the single byte encoded in the code field of a reject message.
 const (
-	RejectMalformed       RejectCode = 0x01
-	RejectInvalid         RejectCode = 0x10
-	RejectObsolete        RejectCode = 0x11
-	RejectDuplicate       RejectCode = 0x12
-	RejectNonstandard     RejectCode = 0x40
-	RejectDust            RejectCode = 0x41
+	// RejectMalformed (0x01) indicates the message could not be decoded.
+	RejectMalformed RejectCode = 0x01
+
+	// RejectInvalid (0x10) indicates a block or transaction which
+	// violates the consensus rules.
+	RejectInvalid RejectCode = 0x10
+
+	// RejectObsolete (0x11) indicates a block or message version which is
+	// no longer supported.
+	RejectObsolete RejectCode = 0x11
+
+	// RejectDuplicate (0x12) indicates a block, transaction or version
+	// message which was already received.
+	RejectDuplicate RejectCode = 0x12
+
+	// RejectNonstandard (0x40) indicates a transaction which is valid but
+	// not accepted by the standardness policy.
+	RejectNonstandard RejectCode = 0x40
+
+	// RejectDust (0x41) indicates a transaction with an output below the
+	// dust threshold.
+	RejectDust RejectCode = 0x41
+
+	// RejectInsufficientFee (0x42) indicates a transaction which does not
+	// pay enough fees or priority to be accepted.
 	RejectInsufficientFee RejectCode = 0x42
-	RejectCheckpoint      RejectCode = 0x43
+
+	// RejectCheckpoint (0x43) indicates a block which conflicts with a
+	// checkpoint.
+	RejectCheckpoint RejectCode = 0x43
 )
 
 // Map of reject codes back strings for pretty printing.
@@ -184,3 +207,16 @@ func NewMsgReject(command string, code RejectCode, reason string) *MsgReject {
 		Reason: reason,
 	}
 }
+
+// FormatReject returns the canonical single line description of a rejected
+// block or transaction, such as "code REJECT_INVALID, reason bad-txns, hash
+// 000000...", for use in both logs and reject messages.  The hash is omitted
+// when it is nil and is otherwise shown in the display byte order of
+// chainhash.Hash.String.  The reason is included verbatim, so callers are
+// expected to sanitize reasons received from remote peers.
+func FormatReject(code RejectCode, reason string, h *chainhash.Hash) string {
+	if h == nil {
+		return fmt.Sprintf("code %v, reason %v", code, reason)
+	}
+	return fmt.Sprintf("code %v, reason %v, hash %v", code, reason, h)
+}
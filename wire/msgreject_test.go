@@ -11,6 +11,7 @@ import (
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
+	"github.com/encrypt-s/navd/chaincfg/chainhash"
 )
 
 // TestRejectCodeStringer tests the stringized output for the reject code type.
@@ -42,6 +43,91 @@ func TestRejectCodeStringer(t *testing.T) {
 
 }
 
+// TestFormatReject ensures rejections are formatted canonically for every
+// reject code, both with and without a hash, and that hashes are shown in
+// display byte order.
+func TestFormatReject(t *testing.T) {
+	// The first stored byte of a hash is the last one displayed.
+	hash := chainhash.Hash{0x01, 0x02}
+	hashStr := "0000000000000000000000000000000000000000000000000000" +
+		"000000000201"
+
+	tests := []struct {
+		code RejectCode
+		want string
+	}{
+		{RejectMalformed, "REJECT_MALFORMED"},
+		{RejectInvalid, "REJECT_INVALID"},
+		{RejectObsolete, "REJECT_OBSOLETE"},
+		{RejectDuplicate, "REJECT_DUPLICATE"},
+		{RejectNonstandard, "REJECT_NONSTANDARD"},
+		{RejectDust, "REJECT_DUST"},
+		{RejectInsufficientFee, "REJECT_INSUFFICIENTFEE"},
+		{RejectCheckpoint, "REJECT_CHECKPOINT"},
+		{0xff, "Unknown RejectCode (255)"},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		want := "code " + test.want + ", reason bad-txns"
+		got := FormatReject(test.code, "bad-txns", nil)
+		if got != want {
+			t.Errorf("FormatReject #%d (no hash)\n got: %s want: %s",
+				i, got, want)
+		}
+
+		want += ", hash " + hashStr
+		got = FormatReject(test.code, "bad-txns", &hash)
+		if got != want {
+			t.Errorf("FormatReject #%d (hash)\n got: %s want: %s", i,
+				got, want)
+		}
+	}
+}
+
+// TestRejectCodeWire ensures every reject code, along with its stringized
+// form, survives a round trip through the wire encoding of a reject message.
+func TestRejectCodeWire(t *testing.T) {
+	codes := []RejectCode{
+		RejectMalformed,
+		RejectInvalid,
+		RejectObsolete,
+		RejectDuplicate,
+		RejectNonstandard,
+		RejectDust,
+		RejectInsufficientFee,
+		RejectCheckpoint,
+	}
+
+	t.Logf("Running %d tests", len(codes))
+	for i, code := range codes {
+		msg := NewMsgReject(CmdTx, code, "reason")
+		var buf bytes.Buffer
+		err := msg.BtcEncode(&buf, ProtocolVersion, BaseEncoding)
+		if err != nil {
+			t.Errorf("BtcEncode #%d error %v", i, err)
+			continue
+		}
+
+		var readMsg MsgReject
+		err = readMsg.BtcDecode(&buf, ProtocolVersion, BaseEncoding)
+		if err != nil {
+			t.Errorf("BtcDecode #%d error %v", i, err)
+			continue
+		}
+		if readMsg.Code != code {
+			t.Errorf("BtcDecode #%d\n got: %v want: %v", i,
+				readMsg.Code, code)
+			continue
+		}
+		if readMsg.Code.String() != code.String() {
+			t.Errorf("String #%d\n got: %s want: %s", i,
+				readMsg.Code.String(), code.String())
+			continue
+		}
+	}
+}
+
 // TestRejectLatest tests the MsgPong API against the latest protocol version.
 func TestRejectLatest(t *testing.T) {
 	pver := ProtocolVersion
@@ -134,6 +134,25 @@ func TestTx(t *testing.T) {
 	}
 }
 
+// TestOutPointBytes ensures outpoints are serialized on the wire exactly as
+// chainhash.OutpointBytes serializes them.
+func TestOutPointBytes(t *testing.T) {
+	hash := chainhash.DoubleHashH([]byte("outpoint"))
+	for _, index := range []uint32{0, 1, 0x01020304, 0xffffffff} {
+		op := NewOutPoint(&hash, index)
+		var buf bytes.Buffer
+		if err := writeOutPoint(&buf, 0, TxVersion, op); err != nil {
+			t.Errorf("writeOutPoint (%v): %v", op, err)
+			continue
+		}
+		want := chainhash.OutpointBytes(&op.Hash, op.Index)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("writeOutPoint (%v)\n got: %x want: %x", op,
+				buf.Bytes(), want)
+		}
+	}
+}
+
 // TestTxHash tests the ability to generate the hash of a transaction accurately.
 func TestTxHash(t *testing.T) {
 	// Hash of first transaction from block 113875.
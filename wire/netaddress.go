@@ -18,7 +18,7 @@ func maxNetAddressPayload(pver uint32) uint32 {
 	plen := uint32(26)
 
 	// NetAddressTimeVersion added a timestamp field.
-	if pver >= NetAddressTimeVersion {
+	if NetAddressHasTimestamp(pver) {
 		// Timestamp 4 bytes.
 		plen += 4
 	}
@@ -94,7 +94,7 @@ func readNetAddress(r io.Reader, pver uint32, na *NetAddress, ts bool) error {
 	// NOTE: The navcoin protocol uses a uint32 for the timestamp so it will
 	// stop working somewhere around 2106.  Also timestamp wasn't added until
 	// protocol version >= NetAddressTimeVersion
-	if ts && pver >= NetAddressTimeVersion {
+	if ts && NetAddressHasTimestamp(pver) {
 		err := readElement(r, (*uint32Time)(&na.Timestamp))
 		if err != nil {
 			return err
@@ -127,7 +127,7 @@ func writeNetAddress(w io.Writer, pver uint32, na *NetAddress, ts bool) error {
 	// NOTE: The navcoin protocol uses a uint32 for the timestamp so it will
 	// stop working somewhere around 2106.  Also timestamp wasn't added until
 	// until protocol version >= NetAddressTimeVersion.
-	if ts && pver >= NetAddressTimeVersion {
+	if ts && NetAddressHasTimestamp(pver) {
 		err := writeElement(w, uint32(na.Timestamp.Unix()))
 		if err != nil {
 			return err
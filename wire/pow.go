@@ -0,0 +1,68 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/encrypt-s/navd/chaincfg/chainhash"
+)
+
+// ErrPoWTooHigh is returned by CheckHeaderPoW when the proof of work hash of a
+// block header is higher than the target difficulty it claims.
+var ErrPoWTooHigh = errors.New("block header proof of work hash is higher " +
+	"than the target difficulty")
+
+// powAlgos maps the navcoin networks to the algorithm which is used to
+// calculate the proof of work hash of their block headers.
+var powAlgos = map[NavCoinNet]chainhash.PowAlgo{
+	MainNet:  chainhash.PowAlgoX13,
+	TestNet:  chainhash.PowAlgoX13,
+	TestNet3: chainhash.PowAlgoX13,
+	SimNet:   chainhash.PowAlgoX13,
+}
+
+// PowAlgoForNet returns the proof of work hashing algorithm used by the passed
+// navcoin network.  An error is returned for unknown networks.
+func PowAlgoForNet(net NavCoinNet) (chainhash.PowAlgo, error) {
+	algo, ok := powAlgos[net]
+	if !ok {
+		return 0, fmt.Errorf("no proof of work algorithm for %v", net)
+	}
+	return algo, nil
+}
+
+// CheckHeaderPoW checks the proof of work of the passed serialized block
+// header against the target difficulty represented by the passed compact bits.
+// The header is hashed with the proof of work algorithm of the passed network,
+// as returned by PowAlgoForNet, and ErrPoWTooHigh is returned when the hash is
+// higher than the target.  Since targets which are zero or negative can never
+// be met, ErrPoWTooHigh is returned for them as well.
+//
+// Note that this only checks the hash against the target.  The caller is
+// responsible for ensuring the target itself is within the range allowed by
+// the network.
+func CheckHeaderPoW(header []byte, bits uint32, net NavCoinNet) error {
+	if len(header) != blockHeaderLen {
+		str := fmt.Sprintf("block header is %d bytes, want %d",
+			len(header), blockHeaderLen)
+		return messageError("CheckHeaderPoW", str)
+	}
+
+	algo, err := PowAlgoForNet(net)
+	if err != nil {
+		return messageError("CheckHeaderPoW", err.Error())
+	}
+	hash, err := chainhash.PowHash(algo, header)
+	if err != nil {
+		return messageError("CheckHeaderPoW", err.Error())
+	}
+
+	if !chainhash.CheckProofOfWork(&hash, bits) {
+		return ErrPoWTooHigh
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// navGenesisHeader is the serialized header of the genesis block of the main
+// navcoin network, which was mined with X13 against bits 0x1f00ffff.
+const navGenesisHeader = "01000000" +
+	"0000000000000000000000000000000000000000000000000000000000000000" +
+	"1ac692738a35b20c57608a4b5946d9d242baafceaf64d73254fdabccc6ee07c5" +
+	"90640e57ffff001f211b0000"
+
+// TestCheckHeaderPoW ensures block header proof of work is checked with the
+// algorithm of the network and that failures are reported as expected.
+func TestCheckHeaderPoW(t *testing.T) {
+	header, err := hex.DecodeString(navGenesisHeader)
+	if err != nil {
+		t.Fatalf("unable to decode header: %v", err)
+	}
+
+	// Ensure the test vector is the expected header.
+	var bh BlockHeader
+	if err := bh.Deserialize(bytes.NewReader(header)); err != nil {
+		t.Fatalf("unable to deserialize header: %v", err)
+	}
+	if bh.Timestamp.Unix() != 1460561040 || bh.Bits != 0x1f00ffff ||
+		bh.Nonce != 6945 {
+
+		t.Fatalf("unexpected genesis header %v", spew.Sdump(bh))
+	}
+
+	// Changing the nonce of the genesis header invalidates its proof of
+	// work.
+	badNonce := append([]byte(nil), header...)
+	badNonce[blockHeaderLen-4] ^= 0x01
+
+	tests := []struct {
+		name   string
+		header []byte
+		bits   uint32
+		net    NavCoinNet
+		err    error
+		valid  bool
+	}{
+		{"genesis", header, 0x1f00ffff, MainNet, nil, true},
+		{"genesis on testnet3", header, 0x1f00ffff, TestNet3, nil, true},
+		{"genesis harder target", header, 0x1e00ffff, MainNet,
+			ErrPoWTooHigh, false},
+		{"genesis zero target", header, 0, MainNet, ErrPoWTooHigh,
+			false},
+		{"bad nonce", badNonce, 0x1f00ffff, MainNet, ErrPoWTooHigh,
+			false},
+		{"short header", header[:blockHeaderLen-1], 0x1f00ffff, MainNet,
+			nil, false},
+		{"unknown network", header, 0x1f00ffff, 0x12345678, nil, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		err := CheckHeaderPoW(test.header, test.bits, test.net)
+		if (err == nil) != test.valid {
+			t.Errorf("CheckHeaderPoW (%s): unexpected error state: "+
+				"%v", test.name, err)
+			continue
+		}
+		if test.err != nil && err != test.err {
+			t.Errorf("CheckHeaderPoW (%s): got error %v, want %v",
+				test.name, err, test.err)
+			continue
+		}
+		if test.err == nil && err != nil {
+			if _, ok := err.(*MessageError); !ok {
+				t.Errorf("CheckHeaderPoW (%s): got error type "+
+					"%T, want *MessageError", test.name, err)
+			}
+		}
+	}
+
+	// Every known network must have a proof of work algorithm.
+	for net := range bnStrings {
+		if _, err := PowAlgoForNet(net); err != nil {
+			t.Errorf("PowAlgoForNet(%v): unexpected error: %v", net,
+				err)
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package wire
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -53,6 +54,114 @@ const (
 	FeeFilterVersion uint32 = 70020
 )
 
+// MinAcceptableProtocolVersion is the lowest protocol version a remote peer may
+// advertise and still be considered compatible.  Peers with older versions are
+// unable to parse the messages this package produces, such as addr messages
+// with multiple addresses.
+const MinAcceptableProtocolVersion = MultipleAddressVersion
+
+// IsProtocolCompatible returns whether the passed protocol version advertised
+// by a remote peer is recent enough to communicate with.
+func IsProtocolCompatible(remotePver uint32) bool {
+	return remotePver >= MinAcceptableProtocolVersion
+}
+
+// SupportsMultipleAddresses returns whether the passed protocol version allows
+// multiple addresses per addr message.
+func SupportsMultipleAddresses(pver uint32) bool {
+	return pver >= MultipleAddressVersion
+}
+
+// NetAddressHasTimestamp returns whether addresses are serialized with their
+// timestamp under the passed protocol version.  Addresses sent to a peer with
+// an older version must use the legacy format without it, since the peer would
+// otherwise misparse the rest of the stream.  Note that some messages, such as
+// version, never include the timestamp regardless of the protocol version.
+func NetAddressHasTimestamp(pver uint32) bool {
+	return pver >= NetAddressTimeVersion
+}
+
+// SupportsPongNonce returns whether the passed protocol version includes a
+// nonce in ping messages which is echoed back in a pong message.  Note that,
+// unlike the other features, this is only the case for versions AFTER
+// BIP0031Version.
+func SupportsPongNonce(pver uint32) bool {
+	return pver > BIP0031Version
+}
+
+// SupportsMempoolMessage returns whether the passed protocol version supports
+// the mempool message.
+func SupportsMempoolMessage(pver uint32) bool {
+	return pver >= BIP0035Version
+}
+
+// SupportsBloomFilters returns whether the passed protocol version supports the
+// bloom filtering related messages and the relay flag of the version message.
+func SupportsBloomFilters(pver uint32) bool {
+	return pver >= BIP0037Version
+}
+
+// SupportsSendHeaders returns whether the passed protocol version supports the
+// sendheaders message, which allows a peer to request that new blocks are
+// announced with a headers message rather than an inv message.
+func SupportsSendHeaders(pver uint32) bool {
+	return pver >= SendHeadersVersion
+}
+
+// SupportsFeeFilter returns whether the passed protocol version supports the
+// feefilter message.
+func SupportsFeeFilter(pver uint32) bool {
+	return pver >= FeeFilterVersion
+}
+
+// Features houses the optional protocol features which are available when
+// communicating with a peer, as returned by NegotiatedFeatures.  It allows the
+// features to be determined once when the version handshake completes rather
+// than whenever a message is sent or received.
+type Features struct {
+	// Bloom is set when the bloom filtering related messages and the
+	// relay flag of the version message are supported.
+	Bloom bool
+
+	// Mempool is set when the mempool message is supported.
+	Mempool bool
+
+	// PongNonce is set when ping messages include a nonce which is echoed
+	// back in a pong message.
+	PongNonce bool
+
+	// MultiAddr is set when addr messages may hold multiple addresses.
+	MultiAddr bool
+
+	// SendHeaders is set when the sendheaders message is supported.
+	SendHeaders bool
+
+	// FeeFilter is set when the feefilter message is supported.
+	FeeFilter bool
+
+	// Witness is set when the peer signalled that it supports witness data
+	// by advertising SFNodeWitness.
+	Witness bool
+}
+
+// NegotiatedFeatures returns the features which are available when
+// communicating with a peer under the passed negotiated protocol version.  Each
+// of them matches the corresponding Supports predicate, such as
+// SupportsBloomFilters.  Support for witness data is not tied to a protocol
+// version, so it is determined from the passed services advertised by the peer
+// instead.
+func NegotiatedFeatures(pver uint32, services ServiceFlag) Features {
+	return Features{
+		Bloom:       SupportsBloomFilters(pver),
+		Mempool:     SupportsMempoolMessage(pver),
+		PongNonce:   SupportsPongNonce(pver),
+		MultiAddr:   SupportsMultipleAddresses(pver),
+		SendHeaders: SupportsSendHeaders(pver),
+		FeeFilter:   SupportsFeeFilter(pver),
+		Witness:     services.Has(SFNodeWitness),
+	}
+}
+
 // ServiceFlag identifies services supported by a navcoin peer.
 type ServiceFlag uint64
 
@@ -88,6 +197,29 @@ const (
 	SFNode2X
 )
 
+const (
+	// SFNodeNetworkLimited is a flag used to indicate a peer is a pruned
+	// node which only serves the most recent blocks (BIP0159).
+	SFNodeNetworkLimited ServiceFlag = 1 << 10
+)
+
+// Service flags which are specific to navcoin are assigned from bit 48 upwards.
+// Bitcoin assigns its service flags from the lowest bit upwards and reserves
+// bits 24 to 31 for temporary experiments, so bits this high are not expected
+// to ever clash with flags defined upstream.
+const (
+	// SFNodeStaking is a flag used to indicate a peer supports the
+	// navcoin specific staking features, such as cold staking and the
+	// community fund.
+	SFNodeStaking ServiceFlag = 1 << 48
+)
+
+// KnownServiceFlags is the set of all service flags defined by this package.
+// Any other bits advertised by a peer have no meaning to it.
+const KnownServiceFlags = SFNodeNetwork | SFNodeGetUTXO | SFNodeBloom |
+	SFNodeWitness | SFNodeXthin | SFNodeBit5 | SFNodeCF | SFNode2X |
+	SFNodeNetworkLimited | SFNodeStaking
+
 // Map of service flags back to their constant names for pretty printing.
 var sfStrings = map[ServiceFlag]string{
 	SFNodeNetwork: "SFNodeNetwork",
@@ -98,10 +230,25 @@ var sfStrings = map[ServiceFlag]string{
 	SFNodeBit5:    "SFNodeBit5",
 	SFNodeCF:      "SFNodeCF",
 	SFNode2X:      "SFNode2X",
+
+	SFNodeNetworkLimited: "SFNodeNetworkLimited",
+
+	SFNodeStaking: "SFNodeStaking",
 }
 
-// orderedSFStrings is an ordered list of service flags from highest to
-// lowest.
+// sfNames is a map of service flag constant names back to their flags for
+// parsing.  It is the reverse of sfStrings.
+var sfNames = func() map[string]ServiceFlag {
+	names := make(map[string]ServiceFlag, len(sfStrings))
+	for flag, name := range sfStrings {
+		names[name] = flag
+	}
+	return names
+}()
+
+// orderedSFStrings is an ordered list of service flags from lowest to
+// highest bit.  It ensures String produces the same output for a given set of
+// flags every time, which iterating over sfStrings would not.
 var orderedSFStrings = []ServiceFlag{
 	SFNodeNetwork,
 	SFNodeGetUTXO,
@@ -111,6 +258,32 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeBit5,
 	SFNodeCF,
 	SFNode2X,
+	SFNodeNetworkLimited,
+	SFNodeStaking,
+}
+
+// Has returns whether all of the passed service flags are set.
+func (f ServiceFlag) Has(flag ServiceFlag) bool {
+	return f&flag == flag
+}
+
+// IsFullNode returns whether the service flags advertise a full, archival node
+// which serves the entire block chain.  That is the case when SFNodeNetwork is
+// set and SFNodeNetworkLimited is not.  Every other bit is ignored.
+//
+// Note that BIP0159 allows archival nodes to set both flags.  Such peers are
+// conservatively not treated as full nodes, so that only peers which advertise
+// no limitation at all are relied upon for historical blocks.
+func (f ServiceFlag) IsFullNode() bool {
+	return f&(SFNodeNetwork|SFNodeNetworkLimited) == SFNodeNetwork
+}
+
+// Known returns the service flags with every bit which is not one of the
+// KnownServiceFlags cleared.  This allows the services advertised by a peer to
+// be stored in a normalized form which only includes the services that are
+// understood.
+func (f ServiceFlag) Known() ServiceFlag {
+	return f & KnownServiceFlags
 }
 
 // String returns the ServiceFlag in human-readable form.
@@ -123,7 +296,7 @@ func (f ServiceFlag) String() string {
 	// Add individual bit flags.
 	s := ""
 	for _, flag := range orderedSFStrings {
-		if f&flag == flag {
+		if f.Has(flag) {
 			s += sfStrings[flag] + "|"
 			f -= flag
 		}
@@ -138,6 +311,102 @@ func (f ServiceFlag) String() string {
 	return s
 }
 
+// Diff returns the service flags which are set in the passed flags but not in
+// f, and those which are set in f but not in the passed flags.  That is, when f
+// holds the services a peer previously advertised and other holds those it
+// advertises now, the services it added and removed.
+func (f ServiceFlag) Diff(other ServiceFlag) (added, removed ServiceFlag) {
+	return other &^ f, f &^ other
+}
+
+// ExplainDiff returns a human-readable description of the difference between f
+// and the passed flags as returned by Diff, such as
+// "+SFNodeBloom -SFNodeWitness".  Added flags are prefixed with '+' and removed
+// ones with '-', in the same order as String lists them, followed by any added
+// and removed flags which aren't accounted for as hex.  An empty string is
+// returned when the flags are the same.
+func (f ServiceFlag) ExplainDiff(other ServiceFlag) string {
+	added, removed := f.Diff(other)
+
+	var parts []string
+	for _, flag := range orderedSFStrings {
+		switch {
+		case added.Has(flag):
+			parts = append(parts, "+"+sfStrings[flag])
+			added -= flag
+		case removed.Has(flag):
+			parts = append(parts, "-"+sfStrings[flag])
+			removed -= flag
+		}
+	}
+	if added != 0 {
+		parts = append(parts, "+0x"+strconv.FormatUint(uint64(added), 16))
+	}
+	if removed != 0 {
+		parts = append(parts, "-0x"+strconv.FormatUint(uint64(removed), 16))
+	}
+	return strings.Join(parts, " ")
+}
+
+// ParseServiceFlag returns the ServiceFlag described by the passed string,
+// which is expected to be in the form produced by String.  That is, the names of
+// the individual flags and, optionally, a 0x-prefixed hex value for any flags
+// without a name, separated by '|'.  Names are case sensitive.
+func ParseServiceFlag(s string) (ServiceFlag, error) {
+	var flags ServiceFlag
+	for _, token := range strings.Split(s, "|") {
+		if strings.HasPrefix(token, "0x") {
+			v, err := strconv.ParseUint(token[2:], 16, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid service flag hex "+
+					"value %q in %q", token, s)
+			}
+			flags |= ServiceFlag(v)
+			continue
+		}
+
+		flag, ok := sfNames[token]
+		if !ok {
+			return 0, fmt.Errorf("unknown service flag %q in %q",
+				token, s)
+		}
+		flags |= flag
+	}
+
+	return flags, nil
+}
+
+// MarshalJSON encodes the service flags as a JSON string in the form produced
+// by String, for example "SFNodeNetwork|SFNodeBloom".
+func (f ServiceFlag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.String())
+}
+
+// UnmarshalJSON decodes service flags from either a JSON string in the form
+// accepted by ParseServiceFlag or, for backwards compatibility, a bare JSON
+// number holding the raw flag bits.
+func (f *ServiceFlag) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		var v uint64
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("invalid service flags %s", data)
+		}
+		*f = ServiceFlag(v)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	flags, err := ParseServiceFlag(s)
+	if err != nil {
+		return err
+	}
+	*f = flags
+	return nil
+}
+
 // NavCoinNet represents which navcoin network a message belongs to.
 type NavCoinNet uint32
 
@@ -174,5 +443,181 @@ func (n NavCoinNet) String() string {
 		return s
 	}
 
-	return fmt.Sprintf("Unknown NavCoinNet (%d)", uint32(n))
+	return fmt.Sprintf("Unknown NavCoinNet (%#08x)", uint32(n))
+}
+
+// bnNames is a map of lowercase network names, which include the lowercase
+// constant names as well as some common aliases, to the navcoin networks they
+// refer to for parsing.  Note that testnet is deliberately absent since the
+// TestNet constant refers to the regression test network, while the name
+// commonly refers to test network version 3.
+var bnNames = map[string]NavCoinNet{
+	"mainnet":        MainNet,
+	"main":           MainNet,
+	"regtest":        TestNet,
+	"regressiontest": TestNet,
+	"testnet3":       TestNet3,
+	"test3":          TestNet3,
+	"simnet":         SimNet,
+	"sim":            SimNet,
+}
+
+// IsKnownNavCoinNet returns whether the passed network is one of the navcoin
+// networks supported by this package.
+func IsKnownNavCoinNet(net NavCoinNet) bool {
+	_, ok := bnStrings[net]
+	return ok
+}
+
+// NavCoinNetFromString returns the navcoin network with the passed name.  The
+// name is case insensitive and may either be the name returned by String or
+// one of the common aliases such as regtest for TestNet.  An error is returned
+// for unknown names as well as for testnet, which is ambiguous between TestNet
+// and TestNet3, so TestNet must be referred to as regtest instead.
+func NavCoinNetFromString(name string) (NavCoinNet, error) {
+	lower := strings.ToLower(name)
+	if lower == "testnet" {
+		return 0, fmt.Errorf("ambiguous navcoin network %q, use regtest "+
+			"or testnet3", name)
+	}
+	net, ok := bnNames[lower]
+	if !ok {
+		return 0, fmt.Errorf("unknown navcoin network %q", name)
+	}
+	return net, nil
+}
+
+// defaultPorts maps the navcoin networks to the default port of their
+// peer-to-peer listeners.
+var defaultPorts = map[NavCoinNet]string{
+	MainNet:  "44440",
+	TestNet:  "18444",
+	TestNet3: "15556",
+	SimNet:   "18555",
+}
+
+// DefaultPort returns the default peer-to-peer port of the passed navcoin
+// network.  An empty string is returned for unknown networks.
+func DefaultPort(net NavCoinNet) string {
+	return defaultPorts[net]
+}
+
+// dnsSeeds maps the navcoin networks to the hostnames of the DNS seeds used to
+// discover peers when bootstrapping.  The regression test and simulation test
+// networks must not have any seeds.
+var dnsSeeds = map[NavCoinNet][]string{
+	MainNet: {
+		"seed.nav.community",
+	},
+	TestNet3: {
+		"176.9.19.245",
+		"46.4.24.136",
+	},
+}
+
+// DNSSeeds returns the hostnames of the DNS seeds of the passed navcoin network.
+// The returned slice is a copy which the caller may modify and is nil for
+// networks without seeds, including unknown networks.
+func DNSSeeds(net NavCoinNet) []string {
+	seeds := dnsSeeds[net]
+	if len(seeds) == 0 {
+		return nil
+	}
+	return append([]string(nil), seeds...)
+}
+
+// ExpectedMagic returns the magic which begins the header of every message on
+// the passed navcoin network, and whether the network is known.  Messages read
+// from a peer whose header starts with a different magic belong to another
+// network, and the peer should be disconnected.
+func ExpectedMagic(net NavCoinNet) (uint32, bool) {
+	if !IsKnownNavCoinNet(net) {
+		return 0, false
+	}
+	return uint32(net), true
+}
+
+// SniffNavCoinNet returns the navcoin network whose magic matches the first 4
+// bytes of the passed data, interpreted as little endian as they are on the
+// wire, and whether it is known.  It is meant to identify which network a
+// captured stream belongs to while diagnosing it and, consistent with the
+// comment on the network constants, must not be used to resynchronize a live
+// stream.
+func SniffNavCoinNet(b []byte) (NavCoinNet, bool) {
+	if len(b) < 4 {
+		return 0, false
+	}
+	net := NavCoinNet(littleEndian.Uint32(b[:4]))
+	if !IsKnownNavCoinNet(net) {
+		return 0, false
+	}
+	return net, true
+}
+
+// bnJSONNames is a map of navcoin networks to the names they are encoded as in
+// JSON.  Unlike the lowercase constant names, TestNet is encoded as regtest so
+// that it can't be mistaken for test network version 3.
+var bnJSONNames = map[NavCoinNet]string{
+	MainNet:  "mainnet",
+	TestNet:  "regtest",
+	TestNet3: "testnet3",
+	SimNet:   "simnet",
+}
+
+// MarshalJSON encodes the network as a JSON string holding its name, for
+// example "mainnet" or "regtest" for TestNet.  Networks which are not known to
+// this package have no name and are encoded as a JSON number holding the raw
+// magic instead.
+func (n NavCoinNet) MarshalJSON() ([]byte, error) {
+	name, ok := bnJSONNames[n]
+	if !ok {
+		return json.Marshal(uint32(n))
+	}
+	return json.Marshal(name)
+}
+
+// UnmarshalJSON decodes the network from either a JSON string holding any of
+// the names accepted by NavCoinNetFromString or a JSON number holding the raw
+// magic.  Numeric magics are accepted even when they are not known to this
+// package for forwards compatibility.
+func (n *NavCoinNet) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] != '"' {
+		var v uint32
+		if err := json.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("invalid navcoin network %s", data)
+		}
+		*n = NavCoinNet(v)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	net, err := NavCoinNetFromString(s)
+	if err != nil {
+		return err
+	}
+	*n = net
+	return nil
+}
+
+// defaultServices maps the navcoin networks to the services a node advertises
+// on them by default.
+var defaultServices = map[NavCoinNet]ServiceFlag{
+	MainNet:  SFNodeNetwork | SFNodeBloom | SFNodeWitness | SFNodeCF,
+	TestNet:  SFNodeNetwork | SFNodeBloom | SFNodeWitness | SFNodeCF,
+	TestNet3: SFNodeNetwork | SFNodeBloom | SFNodeWitness | SFNodeCF,
+	SimNet:   SFNodeNetwork | SFNodeBloom | SFNodeWitness | SFNodeCF,
+}
+
+// DefaultServices returns the services a full node advertises by default on
+// the passed navcoin network, before any services disabled by its
+// configuration are removed.  Only SFNodeNetwork is returned for unknown
+// networks.
+func DefaultServices(net NavCoinNet) ServiceFlag {
+	if services, ok := defaultServices[net]; ok {
+		return services
+	}
+	return SFNodeNetwork
 }
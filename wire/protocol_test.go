@@ -4,7 +4,112 @@
 
 package wire
 
-import "testing"
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestProtocolVersionPredicates tests the boundary protocol version of each of
+// the feature predicates.
+func TestProtocolVersionPredicates(t *testing.T) {
+	tests := []struct {
+		name      string
+		predicate func(uint32) bool
+		first     uint32 // first protocol version supporting the feature
+	}{
+		{"SupportsMultipleAddresses", SupportsMultipleAddresses, 209},
+		{"NetAddressHasTimestamp", NetAddressHasTimestamp, 31402},
+		{"SupportsPongNonce", SupportsPongNonce, 60001},
+		{"SupportsMempoolMessage", SupportsMempoolMessage, 60002},
+		{"SupportsBloomFilters", SupportsBloomFilters, 70001},
+		{"SupportsSendHeaders", SupportsSendHeaders, 70012},
+		{"SupportsFeeFilter", SupportsFeeFilter, 70020},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		if test.predicate(test.first - 1) {
+			t.Errorf("%s(%d): got true want false", test.name,
+				test.first-1)
+		}
+		if !test.predicate(test.first) {
+			t.Errorf("%s(%d): got false want true", test.name,
+				test.first)
+		}
+		if !test.predicate(ProtocolVersion) {
+			t.Errorf("%s(ProtocolVersion): got false want true",
+				test.name)
+		}
+	}
+}
+
+// TestNegotiatedFeatures ensures the features negotiated for several protocol
+// versions and advertised services are the expected ones.
+func TestNegotiatedFeatures(t *testing.T) {
+	tests := []struct {
+		name     string
+		pver     uint32
+		services ServiceFlag
+		want     Features
+	}{
+		{"before multiple addresses", MultipleAddressVersion - 1, 0,
+			Features{}},
+		{"multiple addresses", MultipleAddressVersion, 0,
+			Features{MultiAddr: true}},
+		{"BIP0031", BIP0031Version, 0, Features{MultiAddr: true}},
+		{"BIP0035", BIP0035Version, 0, Features{PongNonce: true,
+			Mempool: true, MultiAddr: true}},
+		{"BIP0037", BIP0037Version, 0, Features{Bloom: true,
+			Mempool: true, PongNonce: true, MultiAddr: true}},
+		{"send headers", SendHeadersVersion, 0, Features{Bloom: true,
+			Mempool: true, PongNonce: true, MultiAddr: true,
+			SendHeaders: true}},
+		{"latest", ProtocolVersion, 0, Features{Bloom: true,
+			Mempool: true, PongNonce: true, MultiAddr: true,
+			SendHeaders: true, FeeFilter: true}},
+		{"latest with witness", ProtocolVersion,
+			SFNodeNetwork | SFNodeWitness, Features{Bloom: true,
+				Mempool: true, PongNonce: true, MultiAddr: true,
+				SendHeaders: true, FeeFilter: true, Witness: true}},
+		{"witness only", 0, SFNodeWitness, Features{Witness: true}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for _, test := range tests {
+		got := NegotiatedFeatures(test.pver, test.services)
+		if got != test.want {
+			t.Errorf("NegotiatedFeatures (%s)\n got: %+v\nwant: %+v",
+				test.name, got, test.want)
+		}
+	}
+}
+
+// TestIsProtocolCompatible tests checking remote protocol versions against the
+// minimum acceptable protocol version.
+func TestIsProtocolCompatible(t *testing.T) {
+	tests := []struct {
+		in   uint32
+		want bool
+	}{
+		{0, false},
+		{MinAcceptableProtocolVersion - 1, false},
+		{MinAcceptableProtocolVersion, true},
+		{MinAcceptableProtocolVersion + 1, true},
+		{ProtocolVersion, true},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := IsProtocolCompatible(test.in)
+		if result != test.want {
+			t.Errorf("IsProtocolCompatible #%d (%d)\n got: %v "+
+				"want: %v", i, test.in, result, test.want)
+			continue
+		}
+	}
+}
 
 // TestServiceFlagStringer tests the stringized output for service flag types.
 func TestServiceFlagStringer(t *testing.T) {
@@ -21,7 +126,12 @@ func TestServiceFlagStringer(t *testing.T) {
 		{SFNodeBit5, "SFNodeBit5"},
 		{SFNodeCF, "SFNodeCF"},
 		{SFNode2X, "SFNode2X"},
-		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|SFNodeWitness|SFNodeXthin|SFNodeBit5|SFNodeCF|SFNode2X|0xffffff00"},
+		{SFNodeNetworkLimited, "SFNodeNetworkLimited"},
+		{SFNodeNetwork | SFNodeNetworkLimited, "SFNodeNetwork|SFNodeNetworkLimited"},
+		{SFNodeStaking, "SFNodeStaking"},
+		{SFNodeNetwork | SFNodeStaking, "SFNodeNetwork|SFNodeStaking"},
+		{SFNodeStaking | 0x2000000000000, "SFNodeStaking|0x2000000000000"},
+		{0xffffffff, "SFNodeNetwork|SFNodeGetUTXO|SFNodeBloom|SFNodeWitness|SFNodeXthin|SFNodeBit5|SFNodeCF|SFNode2X|SFNodeNetworkLimited|0xfffffb00"},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -35,6 +145,318 @@ func TestServiceFlagStringer(t *testing.T) {
 	}
 }
 
+// TestServiceFlagHas tests checking whether service flags are set.
+func TestServiceFlagHas(t *testing.T) {
+	tests := []struct {
+		in   ServiceFlag
+		flag ServiceFlag
+		want bool
+	}{
+		{0, SFNodeNetwork, false},
+		{SFNodeNetwork, SFNodeNetwork, true},
+		{SFNodeNetwork, SFNodeBloom, false},
+		{SFNodeNetwork | SFNodeBloom, SFNodeBloom, true},
+		{SFNodeNetwork | SFNodeBloom, SFNodeNetwork | SFNodeBloom, true},
+		{SFNodeNetwork, SFNodeNetwork | SFNodeBloom, false},
+		{SFNodeNetwork, 0, true},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := test.in.Has(test.flag)
+		if result != test.want {
+			t.Errorf("Has #%d\n got: %v want: %v", i, result,
+				test.want)
+			continue
+		}
+	}
+
+	// The stringized output for multiple flags must always list them in
+	// ascending bit order.
+	flags := SFNodeWitness | SFNodeNetwork | SFNodeBloom
+	want := "SFNodeNetwork|SFNodeBloom|SFNodeWitness"
+	for i := 0; i < 100; i++ {
+		if result := flags.String(); result != want {
+			t.Fatalf("String\n got: %s want: %s", result, want)
+		}
+	}
+}
+
+// TestServiceFlagIsFullNode tests identifying the service flags of full nodes.
+func TestServiceFlagIsFullNode(t *testing.T) {
+	tests := []struct {
+		in   ServiceFlag
+		want bool
+	}{
+		{SFNodeNetwork, true},
+		{SFNodeNetwork | SFNodeWitness | SFNodeStaking | 1<<63, true},
+		{SFNodeNetworkLimited, false},
+		{SFNodeNetworkLimited | SFNodeWitness, false},
+		{SFNodeNetwork | SFNodeNetworkLimited, false},
+		{0, false},
+		{SFNodeBloom | SFNodeWitness, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		if got := test.in.IsFullNode(); got != test.want {
+			t.Errorf("IsFullNode #%d (%v): got %v want %v", i,
+				test.in, got, test.want)
+		}
+	}
+}
+
+// TestServiceFlagKnown tests masking off the service flags which are not
+// defined by this package.
+func TestServiceFlagKnown(t *testing.T) {
+	tests := []struct {
+		in   ServiceFlag
+		want ServiceFlag
+	}{
+		{0, 0},
+		{SFNodeNetwork, SFNodeNetwork},
+		{SFNodeNetwork | 1<<8, SFNodeNetwork},
+		{SFNodeNetwork | SFNodeStaking | 0xff<<24,
+			SFNodeNetwork | SFNodeStaking},
+		{SFNodeNetworkLimited | 1<<11 | 1<<49 | 1<<63,
+			SFNodeNetworkLimited},
+		{1<<8 | 1<<63, 0},
+		{KnownServiceFlags, KnownServiceFlags},
+		{0xffffffffffffffff, KnownServiceFlags},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := test.in.Known()
+		if result != test.want {
+			t.Errorf("Known #%d (%v)\n got: %v want: %v", i, test.in,
+				result, test.want)
+			continue
+		}
+	}
+
+	// Every named service flag must be known and the unknown bits of a
+	// value must still be printed in hex.
+	var named ServiceFlag
+	for _, flag := range orderedSFStrings {
+		named |= flag
+	}
+	if named != KnownServiceFlags {
+		t.Errorf("KnownServiceFlags is %v, want %v", KnownServiceFlags,
+			named)
+	}
+	flags := SFNodeNetwork | 1<<8
+	if got, want := flags.String(), "SFNodeNetwork|0x100"; got != want {
+		t.Errorf("String\n got: %s want: %s", got, want)
+	}
+}
+
+// TestServiceFlagDiff tests computing and explaining the difference between
+// two sets of service flags.
+func TestServiceFlagDiff(t *testing.T) {
+	tests := []struct {
+		old     ServiceFlag
+		new     ServiceFlag
+		added   ServiceFlag
+		removed ServiceFlag
+		explain string
+	}{
+		{0, 0, 0, 0, ""},
+		{SFNodeNetwork, SFNodeNetwork, 0, 0, ""},
+		{
+			old:     SFNodeNetwork,
+			new:     SFNodeNetwork | SFNodeBloom | SFNodeCF,
+			added:   SFNodeBloom | SFNodeCF,
+			explain: "+SFNodeBloom +SFNodeCF",
+		},
+		{
+			old:     SFNodeNetwork | SFNodeWitness | SFNodeStaking,
+			new:     SFNodeNetwork,
+			removed: SFNodeWitness | SFNodeStaking,
+			explain: "-SFNodeWitness -SFNodeStaking",
+		},
+		{
+			old:     SFNodeNetwork | SFNodeWitness,
+			new:     SFNodeNetwork | SFNodeBloom,
+			added:   SFNodeBloom,
+			removed: SFNodeWitness,
+			explain: "+SFNodeBloom -SFNodeWitness",
+		},
+		{
+			old:     SFNodeWitness | 1<<8,
+			new:     SFNodeNetwork | 1<<9,
+			added:   SFNodeNetwork | 1<<9,
+			removed: SFNodeWitness | 1<<8,
+			explain: "+SFNodeNetwork -SFNodeWitness +0x200 -0x100",
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		added, removed := test.old.Diff(test.new)
+		if added != test.added || removed != test.removed {
+			t.Errorf("Diff #%d\n got: +%v -%v want: +%v -%v", i,
+				added, removed, test.added, test.removed)
+			continue
+		}
+		explain := test.old.ExplainDiff(test.new)
+		if explain != test.explain {
+			t.Errorf("ExplainDiff #%d\n got: %q want: %q", i,
+				explain, test.explain)
+			continue
+		}
+	}
+}
+
+// TestParseServiceFlag tests parsing service flags from their stringized form.
+func TestParseServiceFlag(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  ServiceFlag
+		valid bool
+	}{
+		{"0x0", 0, true},
+		{"SFNodeNetwork", SFNodeNetwork, true},
+		{"SFNodeNetwork|SFNodeBloom", SFNodeNetwork | SFNodeBloom, true},
+		{"SFNodeStaking", SFNodeStaking, true},
+		{"SFNodeBloom|SFNodeNetwork", SFNodeNetwork | SFNodeBloom, true},
+		{"SFNodeNetwork|0x1000", SFNodeNetwork | 0x1000, true},
+		{"0xffffffff", 0xffffffff, true},
+		{"", 0, false},
+		{"sfnodenetwork", 0, false},
+		{"SFNodeNetwork|", 0, false},
+		{"SFNodeNetwork|SFNodeBogus", 0, false},
+		{"0x", 0, false},
+		{"0xzz", 0, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result, err := ParseServiceFlag(test.in)
+		if (err == nil) != test.valid {
+			t.Errorf("ParseServiceFlag #%d (%q) unexpected error "+
+				"state: %v", i, test.in, err)
+			continue
+		}
+		if result != test.want {
+			t.Errorf("ParseServiceFlag #%d (%q)\n got: %v want: %v",
+				i, test.in, result, test.want)
+			continue
+		}
+	}
+
+	// Parsing the stringized form of any flags must produce the original
+	// flags.
+	for _, flags := range []ServiceFlag{0, SFNodeNetwork | SFNodeWitness,
+		SFNodeNetworkLimited | 0x8000000000000000, SFNodeStaking,
+		SFNodeBloom | SFNodeStaking, 0xffffffffffffffff} {
+
+		result, err := ParseServiceFlag(flags.String())
+		if err != nil {
+			t.Errorf("ParseServiceFlag(%v): unexpected error: %v",
+				flags, err)
+			continue
+		}
+		if result != flags {
+			t.Errorf("ParseServiceFlag(%v) round trip\n got: %v "+
+				"want: %v", flags, result, flags)
+		}
+	}
+}
+
+// TestServiceFlagJSON tests that service flags round trip through JSON as
+// their stringized form and that bare numbers are still accepted.
+func TestServiceFlagJSON(t *testing.T) {
+	tests := []struct {
+		in   ServiceFlag
+		want string
+	}{
+		{0, `"0x0"`},
+		{SFNodeNetwork, `"SFNodeNetwork"`},
+		{SFNodeNetwork | SFNodeBloom, `"SFNodeNetwork|SFNodeBloom"`},
+		{SFNodeWitness | 0x1000, `"SFNodeWitness|0x1000"`},
+		{SFNodeNetwork | SFNodeStaking, `"SFNodeNetwork|SFNodeStaking"`},
+		{0xff00000000000000, `"0xff00000000000000"`},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		encoded, err := json.Marshal(test.in)
+		if err != nil {
+			t.Errorf("MarshalJSON #%d: unexpected error: %v", i, err)
+			continue
+		}
+		if string(encoded) != test.want {
+			t.Errorf("MarshalJSON #%d\n got: %s want: %s", i,
+				encoded, test.want)
+			continue
+		}
+
+		var result ServiceFlag
+		if err := json.Unmarshal(encoded, &result); err != nil {
+			t.Errorf("UnmarshalJSON #%d: unexpected error: %v", i,
+				err)
+			continue
+		}
+		if result != test.in {
+			t.Errorf("UnmarshalJSON #%d\n got: %v want: %v", i,
+				result, test.in)
+			continue
+		}
+	}
+
+	// Flags embedded in a struct must round trip as well.
+	type peerInfo struct {
+		Services ServiceFlag `json:"services"`
+	}
+	var info peerInfo
+	const encoded = `{"services":"SFNodeNetwork|SFNodeBloom"}`
+	if err := json.Unmarshal([]byte(encoded), &info); err != nil {
+		t.Fatalf("UnmarshalJSON: unexpected error: %v", err)
+	}
+	if info.Services != SFNodeNetwork|SFNodeBloom {
+		t.Fatalf("UnmarshalJSON\n got: %v want: %v", info.Services,
+			SFNodeNetwork|SFNodeBloom)
+	}
+	result, err := json.Marshal(info)
+	if err != nil {
+		t.Fatalf("MarshalJSON: unexpected error: %v", err)
+	}
+	if string(result) != encoded {
+		t.Fatalf("MarshalJSON\n got: %s want: %s", result, encoded)
+	}
+
+	// Bare numbers are accepted for backwards compatibility while
+	// malformed values are rejected.
+	numTests := []struct {
+		in    string
+		want  ServiceFlag
+		valid bool
+	}{
+		{`0`, 0, true},
+		{`5`, SFNodeNetwork | SFNodeBloom, true},
+		{`1024`, SFNodeNetworkLimited, true},
+		{`-1`, 0, false},
+		{`1.5`, 0, false},
+		{`"SFNodeBogus"`, 0, false},
+		{`"0xzz"`, 0, false},
+		{`true`, 0, false},
+	}
+	for i, test := range numTests {
+		var result ServiceFlag
+		err := json.Unmarshal([]byte(test.in), &result)
+		if (err == nil) != test.valid {
+			t.Errorf("UnmarshalJSON #%d (%s) unexpected error "+
+				"state: %v", i, test.in, err)
+			continue
+		}
+		if result != test.want {
+			t.Errorf("UnmarshalJSON #%d (%s)\n got: %v want: %v",
+				i, test.in, result, test.want)
+		}
+	}
+}
+
 // TestNavCoinNetStringer tests the stringized output for navcoin net types.
 func TestNavCoinNetStringer(t *testing.T) {
 	tests := []struct {
@@ -45,7 +467,8 @@ func TestNavCoinNetStringer(t *testing.T) {
 		{TestNet, "TestNet"},
 		{TestNet3, "TestNet3"},
 		{SimNet, "SimNet"},
-		{0xffffffff, "Unknown NavCoinNet (4294967295)"},
+		{0xffffffff, "Unknown NavCoinNet (0xffffffff)"},
+		{0x0000beef, "Unknown NavCoinNet (0x0000beef)"},
 	}
 
 	t.Logf("Running %d tests", len(tests))
@@ -58,3 +481,383 @@ func TestNavCoinNetStringer(t *testing.T) {
 		}
 	}
 }
+
+// TestNavCoinNetDistinct ensures the magic values of the known navcoin networks
+// don't collide with one another.
+func TestNavCoinNetDistinct(t *testing.T) {
+	nets := []NavCoinNet{MainNet, TestNet, TestNet3, SimNet}
+	seen := make(map[NavCoinNet]int, len(nets))
+	for i, net := range nets {
+		if j, ok := seen[net]; ok {
+			t.Errorf("network #%d (%v) has the same magic as "+
+				"network #%d", i, net, j)
+			continue
+		}
+		seen[net] = i
+	}
+}
+
+// TestIsKnownNavCoinNet tests recognizing the supported navcoin networks.
+func TestIsKnownNavCoinNet(t *testing.T) {
+	tests := []struct {
+		in   NavCoinNet
+		want bool
+	}{
+		{MainNet, true},
+		{TestNet, true},
+		{TestNet3, true},
+		{SimNet, true},
+		{0, false},
+		{0xffffffff, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := IsKnownNavCoinNet(test.in)
+		if result != test.want {
+			t.Errorf("IsKnownNavCoinNet #%d (%v)\n got: %v want: %v",
+				i, test.in, result, test.want)
+			continue
+		}
+	}
+}
+
+// TestNavCoinNetFromString tests parsing navcoin networks from their names and
+// aliases.
+func TestNavCoinNetFromString(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  NavCoinNet
+		valid bool
+	}{
+		{"mainnet", MainNet, true},
+		{"MainNet", MainNet, true},
+		{"main", MainNet, true},
+		{"testnet", 0, false},
+		{"TestNet", 0, false},
+		{"regtest", TestNet, true},
+		{"RegTest", TestNet, true},
+		{"regressiontest", TestNet, true},
+		{"testnet3", TestNet3, true},
+		{"TESTNET3", TestNet3, true},
+		{"test3", TestNet3, true},
+		{"simnet", SimNet, true},
+		{"sim", SimNet, true},
+		{"", 0, false},
+		{"testnet4", 0, false},
+		{" mainnet", 0, false},
+		{"0x20345080", 0, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result, err := NavCoinNetFromString(test.in)
+		if (err == nil) != test.valid {
+			t.Errorf("NavCoinNetFromString #%d (%q) unexpected "+
+				"error state: %v", i, test.in, err)
+			continue
+		}
+		if result != test.want {
+			t.Errorf("NavCoinNetFromString #%d (%q)\n got: %v "+
+				"want: %v", i, test.in, result, test.want)
+			continue
+		}
+	}
+
+	// The name returned by String must parse back to the same network,
+	// apart from the ambiguous name of TestNet.
+	for net := range bnStrings {
+		if net == TestNet {
+			continue
+		}
+		result, err := NavCoinNetFromString(net.String())
+		if err != nil || result != net {
+			t.Errorf("NavCoinNetFromString(%q): got %v, %v want %v",
+				net.String(), result, err, net)
+		}
+	}
+}
+
+// TestExpectedMagic ensures the expected magic of each network is its value
+// and begins the header of the messages written for it.
+func TestExpectedMagic(t *testing.T) {
+	tests := []struct {
+		net   NavCoinNet
+		magic uint32
+		ok    bool
+	}{
+		{MainNet, 0x20345080, true},
+		{TestNet, 0xdab5bffa, true},
+		{TestNet3, 0x2052a23f, true},
+		{SimNet, 0x12141c16, true},
+		{0xffffffff, 0, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		magic, ok := ExpectedMagic(test.net)
+		if ok != test.ok || magic != test.magic {
+			t.Errorf("ExpectedMagic #%d (%v)\n got: %#08x, %v "+
+				"want: %#08x, %v", i, test.net, magic, ok,
+				test.magic, test.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if magic != uint32(test.net) {
+			t.Errorf("ExpectedMagic #%d (%v): magic %#08x does not "+
+				"match network", i, test.net, magic)
+			continue
+		}
+
+		var buf bytes.Buffer
+		err := WriteMessage(&buf, NewMsgVerAck(), ProtocolVersion,
+			test.net)
+		if err != nil {
+			t.Errorf("WriteMessage #%d error %v", i, err)
+			continue
+		}
+		header := buf.Bytes()
+		if got := littleEndian.Uint32(header[:4]); got != magic {
+			t.Errorf("WriteMessage #%d (%v): header magic %#08x, "+
+				"want %#08x", i, test.net, got, magic)
+			continue
+		}
+	}
+}
+
+// TestSniffNavCoinNet tests identifying the network of a stream from its
+// leading magic bytes.
+func TestSniffNavCoinNet(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want NavCoinNet
+		ok   bool
+	}{
+		{[]byte{0x80, 0x50, 0x34, 0x20}, MainNet, true},
+		{[]byte{0xfa, 0xbf, 0xb5, 0xda}, TestNet, true},
+		{[]byte{0x3f, 0xa2, 0x52, 0x20}, TestNet3, true},
+		{[]byte{0x16, 0x1c, 0x14, 0x12}, SimNet, true},
+
+		// Start of a mainnet version message.
+		{[]byte{
+			0x80, 0x50, 0x34, 0x20, 0x76, 0x65, 0x72, 0x73,
+			0x69, 0x6f, 0x6e, 0x00, 0x00, 0x00, 0x00, 0x00,
+		}, MainNet, true},
+
+		// Big endian mainnet magic.
+		{[]byte{0x20, 0x34, 0x50, 0x80}, 0, false},
+		{[]byte{0xde, 0xad, 0xbe, 0xef, 0x00}, 0, false},
+		{[]byte{0x80, 0x50, 0x34}, 0, false},
+		{nil, 0, false},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result, ok := SniffNavCoinNet(test.in)
+		if ok != test.ok {
+			t.Errorf("SniffNavCoinNet #%d (%x) ok\n got: %v want: %v",
+				i, test.in, ok, test.ok)
+			continue
+		}
+		if result != test.want {
+			t.Errorf("SniffNavCoinNet #%d (%x)\n got: %v want: %v",
+				i, test.in, result, test.want)
+			continue
+		}
+	}
+}
+
+// TestDefaultPort tests the default peer-to-peer port of each network.
+func TestDefaultPort(t *testing.T) {
+	tests := []struct {
+		net  NavCoinNet
+		want string
+	}{
+		{MainNet, "44440"},
+		{TestNet, "18444"},
+		{TestNet3, "15556"},
+		{SimNet, "18555"},
+		{0xffffffff, ""},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := DefaultPort(test.net)
+		if result != test.want {
+			t.Errorf("DefaultPort #%d (%v)\n got: %q want: %q", i,
+				test.net, result, test.want)
+			continue
+		}
+	}
+
+	// Every known network must have a distinct default port.
+	seen := make(map[string]NavCoinNet)
+	for net := range bnStrings {
+		port := DefaultPort(net)
+		if port == "" {
+			t.Errorf("DefaultPort: no port for %v", net)
+			continue
+		}
+		if other, ok := seen[port]; ok {
+			t.Errorf("DefaultPort: %v and %v share port %s", net,
+				other, port)
+		}
+		seen[port] = net
+	}
+}
+
+// TestDNSSeeds tests the DNS seeds of each network.
+func TestDNSSeeds(t *testing.T) {
+	tests := []struct {
+		net  NavCoinNet
+		want []string
+	}{
+		{MainNet, []string{"seed.nav.community"}},
+		{TestNet, nil},
+		{TestNet3, []string{"176.9.19.245", "46.4.24.136"}},
+		{SimNet, nil},
+		{0xffffffff, nil},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := DNSSeeds(test.net)
+		if !reflect.DeepEqual(result, test.want) {
+			t.Errorf("DNSSeeds #%d (%v)\n got: %v want: %v", i,
+				test.net, result, test.want)
+			continue
+		}
+	}
+
+	// Modifying the returned seeds must not affect later calls.
+	seeds := DNSSeeds(MainNet)
+	seeds[0] = "example.com"
+	if result := DNSSeeds(MainNet); result[0] != "seed.nav.community" {
+		t.Errorf("DNSSeeds: modified seeds returned %v", result)
+	}
+}
+
+// TestNavCoinNetJSON tests that navcoin networks round trip through JSON by
+// name, that numeric magics are accepted and that garbage is rejected.
+func TestNavCoinNetJSON(t *testing.T) {
+	tests := []struct {
+		in   NavCoinNet
+		want string
+	}{
+		{MainNet, `"mainnet"`},
+		{TestNet, `"regtest"`},
+		{TestNet3, `"testnet3"`},
+		{SimNet, `"simnet"`},
+		{0xffffffff, `4294967295`},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		encoded, err := json.Marshal(test.in)
+		if err != nil {
+			t.Errorf("MarshalJSON #%d: unexpected error: %v", i, err)
+			continue
+		}
+		if string(encoded) != test.want {
+			t.Errorf("MarshalJSON #%d\n got: %s want: %s", i,
+				encoded, test.want)
+			continue
+		}
+
+		var result NavCoinNet
+		if err := json.Unmarshal(encoded, &result); err != nil {
+			t.Errorf("UnmarshalJSON #%d: unexpected error: %v", i,
+				err)
+			continue
+		}
+		if result != test.in {
+			t.Errorf("UnmarshalJSON #%d\n got: %v want: %v", i,
+				result, test.in)
+			continue
+		}
+	}
+
+	// Every known network must round trip.
+	for net := range bnStrings {
+		encoded, err := json.Marshal(net)
+		if err != nil {
+			t.Errorf("MarshalJSON(%v): unexpected error: %v", net, err)
+			continue
+		}
+		var result NavCoinNet
+		if err := json.Unmarshal(encoded, &result); err != nil ||
+			result != net {
+
+			t.Errorf("UnmarshalJSON(%s): got %v, %v want %v",
+				encoded, result, err, net)
+		}
+	}
+
+	unmarshalTests := []struct {
+		in    string
+		want  NavCoinNet
+		valid bool
+	}{
+		{`"MainNet"`, MainNet, true},
+		{`"regtest"`, TestNet, true},
+		{`"testnet"`, 0, false},
+		{`540299392`, MainNet, true},
+		{`305419896`, 0x12345678, true},
+		{`"testnet4"`, 0, false},
+		{`"0x20345080"`, 0, false},
+		{`-1`, 0, false},
+		{`4294967296`, 0, false},
+		{`1.5`, 0, false},
+		{`null`, 0, true},
+		{`{}`, 0, false},
+		{`"mainnet`, 0, false},
+	}
+	for i, test := range unmarshalTests {
+		var result NavCoinNet
+		err := json.Unmarshal([]byte(test.in), &result)
+		if (err == nil) != test.valid {
+			t.Errorf("UnmarshalJSON #%d (%s) unexpected error "+
+				"state: %v", i, test.in, err)
+			continue
+		}
+		if result != test.want {
+			t.Errorf("UnmarshalJSON #%d (%s)\n got: %v want: %v",
+				i, test.in, result, test.want)
+		}
+	}
+}
+
+// TestDefaultServices tests the services advertised by default on each
+// network.
+func TestDefaultServices(t *testing.T) {
+	fullNode := SFNodeNetwork | SFNodeBloom | SFNodeWitness | SFNodeCF
+	tests := []struct {
+		net  NavCoinNet
+		want ServiceFlag
+	}{
+		{MainNet, fullNode},
+		{TestNet, fullNode},
+		{TestNet3, fullNode},
+		{SimNet, fullNode},
+		{0xffffffff, SFNodeNetwork},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		result := DefaultServices(test.net)
+		if result != test.want {
+			t.Errorf("DefaultServices #%d (%v)\n got: %v want: %v",
+				i, test.net, result, test.want)
+			continue
+		}
+	}
+
+	// Every known network must have its default services defined.
+	for net := range bnStrings {
+		if _, ok := defaultServices[net]; !ok {
+			t.Errorf("DefaultServices: no services for %v", net)
+		}
+	}
+}